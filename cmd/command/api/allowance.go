@@ -7,17 +7,25 @@ import (
 	"github.com/StevenRojas/sharedWallet/config"
 	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
 	"github.com/spf13/cobra"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
 )
 
 var ErrInvalidAllowanceAction = errors.New("invalid allowance action")
 var ErrInvalidAmountAction = errors.New("amount should be a positive value")
+var ErrMissingTargetAddress = errors.New("target address is required")
+var ErrInvalidSort = errors.New("sort should be allowance, beneficiary or changed")
 
 var allowanceActions = map[string]struct{}{
 	"set": {},
 	"get": {},
 	"increase": {},
 	"reduce": {},
+	"list": {},
 }
 
 // NewAllowanceCommand creates the allowance command
@@ -26,30 +34,39 @@ func NewAllowanceCommand(ctx context.Context) *cobra.Command {
 		action string
 		targetAddress string
 		amount int64
+		sortBy string
+		block string
+		at string
 	)
 	allowanceCommand := &cobra.Command{
 		Use:   "allowance",
 		Short: "Change the allowance for a beneficiary",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := runAllowance(ctx, action, targetAddress, amount)
-			if err != nil {
-				fmt.Println("ERROR: " + err.Error())
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if action == blockchain.ListAction {
+				return listAllowances(ctx, sortBy, block, at)
 			}
+			return runAllowance(ctx, action, targetAddress, amount, block, at)
 		},
 	}
 
-	allowanceCommand.Flags().StringVar(&action, "action", "", "Action to perform: set, get, increase or reduce")
+	allowanceCommand.Flags().StringVar(&action, "action", "", "Action to perform: set, get, increase, reduce or list")
 	allowanceCommand.Flags().Int64Var(&amount, "amount", 0, "Amount")
 	allowanceCommand.Flags().StringVarP(&targetAddress, "target.address", "t", "", "Target address")
+	allowanceCommand.Flags().StringVar(&sortBy, "sort", "allowance", "List order: allowance, beneficiary or changed")
+	allowanceCommand.Flags().Float64("budget.limit", 0, "Maximum of the allowances as a ratio of the contract balance checked before set and increase, i.e. 1.5")
+	allowanceCommand.Flags().Bool("budget.refuse", false, "Refuse the allowance changes past the budget limit instead of warning")
+	AddBlockFlags(allowanceCommand, &block, &at)
 	_ = allowanceCommand.MarkFlagRequired("action")
-	_ = allowanceCommand.MarkFlagRequired("target.address")
 	return allowanceCommand
 }
 
-func runAllowance(ctx context.Context, action string, targetAddress string, amount int64) error {
+func runAllowance(ctx context.Context, action string, targetAddress string, amount int64, block string, at string) error {
 	if _, ok := allowanceActions[action]; !ok {
 		return ErrInvalidAllowanceAction
 	}
+	if targetAddress == "" {
+		return ErrMissingTargetAddress
+	}
 	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
 	defer cancel()
 	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
@@ -60,20 +77,73 @@ func runAllowance(ctx context.Context, action string, targetAddress string, amou
 
 	switch action {
 	case blockchain.GetAction:
-		allowance, err := runner.GetAllowance(ctx, client, targetAddress)
+		blockRef, err := readBlock(ctx, client, block, at)
+		if err != nil {
+			return err
+		}
+		allowance, err := runner.GetAllowance(ctx, client, targetAddress, blockRef)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Current allowance for address %s is %d\n", targetAddress, allowance)
+		return output.Print(config.App.Output, beneficiaryAllowance{Beneficiary: targetAddress, Allowance: allowance, Block: blockRef.Number})
 	default:
 		if amount <= 0 {
 			return ErrInvalidAmountAction
 		}
-		err = runner.ChangeAllowance(ctx, client, action, targetAddress, amount)
-		if err != nil {
+		if err = checkBudget(ctx, client, action, targetAddress, amount); err != nil {
 			return err
 		}
+		info, err := runner.ChangeAllowance(ctx, client, action, targetAddress, amount)
+		return printTransaction(info, targetAddress, amount, err)
 	}
+}
+
+// beneficiaryAllowance the allowance of a beneficiary at a block in wei, the block is nil when it is read at the pending block
+type beneficiaryAllowance struct {
+	Beneficiary string   `json:"beneficiary"`
+	Allowance   *big.Int `json:"allowance"`
+	Block       *big.Int `json:"block"`
+}
+
+// Columns returns the allowance columns
+func (a beneficiaryAllowance) Columns() []string {
+	return []string{"beneficiary", "allowance", "block"}
+}
+
+// Rows returns the allowance row in ether
+func (a beneficiaryAllowance) Rows() [][]string {
+	return [][]string{{a.Beneficiary, blockchain.FormatEther(a.Allowance), blockName(a.Block)}}
+}
 
+// Text prints the allowance in ether
+func (a beneficiaryAllowance) Text(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Current allowance for address %s is %s\n", a.Beneficiary, blockchain.FormatEther(a.Allowance))
+	return err
+}
+
+// checkBudget warns or refuses the allowance changes that would push the allowances past the budget limit
+func checkBudget(ctx context.Context, client *ethclient.Client, action string, targetAddress string, amount int64) error {
+	limit := config.App.Budget.Limit
+	if limit <= 0 || (action != blockchain.SetAction && action != blockchain.IncreaseAction) {
+		return nil
+	}
+	budget, err := blockchain.ReadBudget(ctx, client, config.App.Contract.Address, blockchain.BlockRef{})
+	if err != nil {
+		return err
+	}
+	wei, err := blockchain.ParseEther(strconv.FormatInt(amount, 10))
+	if err != nil {
+		return err
+	}
+	allowances := budget.After(action, targetAddress, wei)
+	if !budget.Exceeds(allowances, limit) {
+		return nil
+	}
+	if config.App.Budget.Refuse {
+		return blockchain.ErrOverCommitted
+	}
+	// the warning goes to the standard error so the result output stays parsable
+	_, _ = fmt.Fprintf(os.Stderr, "WARNING: the allowances would be %s for a contract balance of %s, over the limit of %g times the balance\n",
+		blockchain.FormatEther(allowances), blockchain.FormatEther(budget.Balance), limit)
 	return nil
 }
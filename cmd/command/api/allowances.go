@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// allowanceOrders compare functions of the list orders, the largest allowances and latest changes go first
+var allowanceOrders = map[string]func(a, b blockchain.BeneficiaryAllowance) bool{
+	"allowance": func(a, b blockchain.BeneficiaryAllowance) bool {
+		return a.Allowance.Cmp(b.Allowance) > 0
+	},
+	"beneficiary": func(a, b blockchain.BeneficiaryAllowance) bool {
+		return strings.ToLower(a.Beneficiary) < strings.ToLower(b.Beneficiary)
+	},
+	"changed": func(a, b blockchain.BeneficiaryAllowance) bool {
+		return a.LastChangeBlock > b.LastChangeBlock
+	},
+}
+
+// listAllowances prints the beneficiaries with a non-zero allowance at a block
+func listAllowances(ctx context.Context, sortBy string, block string, at string) error {
+	less, ok := allowanceOrders[sortBy]
+	if !ok {
+		return ErrInvalidSort
+	}
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.Address)
+	if err != nil {
+		return err
+	}
+	runner := blockchain.NewAllowanceRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
+	blockRef, err := blockchain.ResolveBlock(ctx, client, block, at)
+	if err != nil {
+		return err
+	}
+	allowances, err := runner.ListAllowances(ctx, client, blockRef)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(allowances, func(i, j int) bool {
+		return less(allowances[i], allowances[j])
+	})
+	return output.Print(config.App.Output, allowanceList(allowances))
+}
+
+// allowanceList the allowances printed by the output formats
+type allowanceList []blockchain.BeneficiaryAllowance
+
+// Columns returns the allowance columns
+func (l allowanceList) Columns() []string {
+	return []string{"beneficiary", "allowance", "last_change", "last_change_block"}
+}
+
+// Rows returns the allowance rows
+func (l allowanceList) Rows() [][]string {
+	rows := make([][]string, 0, len(l))
+	for _, a := range l {
+		rows = append(rows, []string{a.Beneficiary, blockchain.FormatEther(a.Allowance), a.LastChange.UTC().Format(time.RFC3339),
+			strconv.FormatUint(a.LastChangeBlock, 10)})
+	}
+	return rows
+}
+
+// MarshalJSON returns the allowances, an empty list is an empty array
+func (l allowanceList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]blockchain.BeneficiaryAllowance(l))
+}
@@ -6,8 +6,14 @@ import (
 	"fmt"
 	"github.com/StevenRojas/sharedWallet/config"
 	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/price"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
 	"github.com/spf13/cobra"
+	"io"
+	"math/big"
+	"strings"
+	"time"
 )
 
 var (
@@ -26,25 +32,27 @@ func NewBalanceCommand(ctx context.Context) *cobra.Command {
 	var (
 		of string
 		targetAddress string
+		block string
+		at string
 	)
 	balanceCommand := &cobra.Command{
 		Use:   "balance",
 		Short: "Get the balance of an address or contract",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := runBalance(ctx, of, targetAddress)
-			if err != nil {
-				fmt.Println("ERROR: " + err.Error())
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBalance(ctx, of, targetAddress, block, at)
 		},
 	}
 
-	balanceCommand.Flags().StringVar(&of, "of", "", "Balance of: address, contract")
+	balanceCommand.Flags().StringVar(&of, "of", "", "Balance of: address, contract or owner")
 	balanceCommand.Flags().StringVarP(&targetAddress, "target.address", "t", "", "Target address")
+	balanceCommand.Flags().String("prices.file", "", "CSV file of date and ether price rows used to value the balance")
+	balanceCommand.Flags().String("prices.currency", "USD", "Currency of the price table")
+	AddBlockFlags(balanceCommand, &block, &at)
 	_ = balanceCommand.MarkFlagRequired("of")
 	return balanceCommand
 }
 
-func runBalance(ctx context.Context, of string, targetAddress string) error {
+func runBalance(ctx context.Context, of string, targetAddress string, block string, at string) error {
 	if _, ok := balanceOfList[of]; !ok {
 		return ErrInvalidBalanceAction
 	}
@@ -55,27 +63,100 @@ func runBalance(ctx context.Context, of string, targetAddress string) error {
 	if err != nil {
 		return err
 	}
+	blockRef, err := readBlock(ctx, client, block, at)
+	if err != nil {
+		return err
+	}
 	runner := blockchain.NewBalanceRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
 
+	result := addressBalance{Of: of, Address: targetAddress, Block: blockRef.Number}
 	switch of {
 	case blockchain.ContractBalance:
-		balance, err := runner.GetContractBalance(ctx, client)
-		if err != nil {
+		result.Address = config.App.Contract.Address
+		result.Balance, err = runner.GetContractBalance(ctx, client, blockRef)
+	case blockchain.OwnerBalance:
+		owner := blockchain.NewOwnerRunner(config.App.Blockchain.PrivateKey, config.App.Contract.Address)
+		if result.Address, err = owner.GetOwner(ctx, client, blockRef); err != nil {
 			return err
 		}
-		fmt.Printf("The contract balance is %d\n", balance)
+		result.Balance, err = runner.GetAddressBalance(ctx, client, result.Address, blockRef)
 	case blockchain.AddressBalance:
 		if targetAddress == "" {
 			return ErrInvalidBalanceAddress
 		}
-		balance, err := runner.GetAddressBalance(ctx, client, targetAddress)
-		if err != nil {
-			return err
-		}
-		fmt.Printf("The contract balance is %d\n", balance)
+		result.Balance, err = runner.GetAddressBalance(ctx, client, targetAddress, blockRef)
+	}
+	if err != nil {
+		return err
+	}
+	if result.Value, err = balanceValue(ctx, client, result.Balance, blockRef); err != nil {
+		return err
+	}
+	if result.Value != nil {
+		result.Currency = config.App.Prices.Currency
+	}
+	return output.Print(config.App.Output, result)
+}
+
+// addressBalance the balance in wei of the contract, its owner or an address at a block, the block is nil when it is read
+// at the pending block. The value is set when there is a price table
+type addressBalance struct {
+	Of       string       `json:"of"`
+	Address  string       `json:"address"`
+	Balance  *big.Int     `json:"balance"`
+	Block    *big.Int     `json:"block"`
+	Value    *price.Value `json:"value,omitempty"`
+	Currency string       `json:"currency,omitempty"`
+}
+
+// Columns returns the balance columns, the value column is added when the balance is valued
+func (b addressBalance) Columns() []string {
+	columns := []string{"of", "address", "balance", "block"}
+	if b.Value != nil {
+		columns = append(columns, "value_"+strings.ToLower(b.Currency))
 	}
+	return columns
+}
 
+// Rows returns the balance row in ether
+func (b addressBalance) Rows() [][]string {
+	row := []string{b.Of, b.Address, blockchain.FormatEther(b.Balance), blockName(b.Block)}
+	if b.Value != nil {
+		row = append(row, b.Value.String())
+	}
+	return [][]string{row}
+}
 
-	return nil
+// Text prints the balance in ether with its value
+func (b addressBalance) Text(w io.Writer) error {
+	subject := "The contract balance"
+	switch b.Of {
+	case blockchain.OwnerBalance:
+		subject = "The balance of the owner " + b.Address
+	case blockchain.AddressBalance:
+		subject = "The balance of " + b.Address
+	}
+	value := ""
+	if b.Value != nil {
+		value = fmt.Sprintf(" (%s %s)", b.Value, b.Currency)
+	}
+	_, err := fmt.Fprintf(w, "%s is %s%s\n", subject, blockchain.FormatEther(b.Balance), value)
+	return err
 }
 
+// balanceValue returns the value of a balance at the time of its block with the configured price table, nil when there
+// is no table. The balances read at the pending block are valued at the time of the latest block
+func balanceValue(ctx context.Context, client *ethclient.Client, balance *big.Int, block blockchain.BlockRef) (*price.Value, error) {
+	if config.App.Prices.File == "" {
+		return nil, nil
+	}
+	prices, err := price.Load(config.App.Prices.File)
+	if err != nil {
+		return nil, err
+	}
+	header, err := client.HeaderByNumber(ctx, block.Number)
+	if err != nil {
+		return nil, err
+	}
+	return prices.Value(balance, time.Unix(int64(header.Time), 0))
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/spf13/cobra"
+	"math/big"
+)
+
+// AddBlockFlags adds the flags of the block the state is read at
+func AddBlockFlags(cmd *cobra.Command, block *string, at *string) {
+	cmd.Flags().StringVar(block, "block", blockchain.LatestBlock, "Block the state is read at: a block number, latest or pending")
+	cmd.Flags().StringVar(at, "at", "", "Time the state is read at, the last block mined before it: YYYY-MM-DD (end of the day), RFC3339 or unix seconds")
+}
+
+// readBlock returns the block of the block flags, the latest block is pinned to its number so the results show the
+// block they were read at
+func readBlock(ctx context.Context, client *ethclient.Client, block string, at string) (blockchain.BlockRef, error) {
+	blockRef, err := blockchain.ResolveBlock(ctx, client, block, at)
+	if err != nil || blockRef.Pending {
+		return blockRef, err
+	}
+	blockRef.Number, err = blockRef.Resolve(ctx, client)
+	return blockRef, err
+}
+
+// blockName returns the block number of a read in the text outputs, nil is the pending block
+func blockName(number *big.Int) string {
+	if number == nil {
+		return blockchain.PendingBlock
+	}
+	return number.String()
+}
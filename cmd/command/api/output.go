@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+// transaction the result of the commands sending a transaction, the amounts are in wei
+type transaction struct {
+	blockchain.TransactionInfo
+	Target string   `json:"target,omitempty"`
+	Amount *big.Int `json:"amount,omitempty"`
+}
+
+// printTransaction prints the mined transactions, the reverted ones are printed before returning their error
+func printTransaction(info blockchain.TransactionInfo, target string, amount int64, err error) error {
+	if info.TxHash == "" {
+		return err
+	}
+	result := transaction{TransactionInfo: info, Target: target}
+	if amount > 0 {
+		result.Amount, _ = blockchain.ParseEther(strconv.FormatInt(amount, 10))
+	}
+	if printErr := output.Print(config.App.Output, result); printErr != nil {
+		return printErr
+	}
+	return err
+}
+
+// Columns returns the transaction columns
+func (t transaction) Columns() []string {
+	return []string{"operation", "target", "amount", "tx_hash", "block", "gas", "gas_price", "cost"}
+}
+
+// Rows returns the transaction row, the amounts are in ether except the gas price in wei
+func (t transaction) Rows() [][]string {
+	amount := ""
+	if t.Amount != nil {
+		amount = blockchain.FormatEther(t.Amount)
+	}
+	return [][]string{{t.Operation, t.Target, amount, t.TxHash, strconv.FormatUint(t.Block, 10), strconv.FormatUint(t.Gas, 10),
+		t.GasPrice.String(), blockchain.FormatEther(t.Cost)}}
+}
+
+// Text prints the mined transaction
+func (t transaction) Text(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s mined in block %d, transaction %s costing %s ether\n", t.Operation, t.Block, t.TxHash,
+		blockchain.FormatEther(t.Cost))
+	return err
+}
@@ -7,7 +7,10 @@ import (
 	"github.com/StevenRojas/sharedWallet/config"
 	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
 	"github.com/spf13/cobra"
+	"io"
+	"math/big"
 )
 
 var (
@@ -25,25 +28,25 @@ func NewOwnershipCommand(ctx context.Context) *cobra.Command {
 	var (
 		action string
 		targetAddress string
+		block string
+		at string
 	)
 	ownershipCommand := &cobra.Command{
 		Use:   "ownership",
 		Short: "Get or transfer contract ownership",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := runOwnership(ctx, action, targetAddress)
-			if err != nil {
-				fmt.Println("ERROR: " + err.Error())
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOwnership(ctx, action, targetAddress, block, at)
 		},
 	}
 
 	ownershipCommand.Flags().StringVar(&action, "action", "", "Ownership action: get, transfer")
 	ownershipCommand.Flags().StringVarP(&targetAddress, "target.address", "t", "", "Target address")
+	AddBlockFlags(ownershipCommand, &block, &at)
 	_ = ownershipCommand.MarkFlagRequired("action")
 	return ownershipCommand
 }
 
-func runOwnership(ctx context.Context, action string, targetAddress string) error {
+func runOwnership(ctx context.Context, action string, targetAddress string, block string, at string) error {
 	if _, ok := ownershipActions[action]; !ok {
 		return ErrInvalidOwnershipAction
 	}
@@ -58,21 +61,44 @@ func runOwnership(ctx context.Context, action string, targetAddress string) erro
 
 	switch action {
 	case blockchain.GetAction:
-		owner, err := runner.GetOwner(ctx, client)
+		blockRef, err := readBlock(ctx, client, block, at)
 		if err != nil {
 			return err
 		}
-		fmt.Printf("Current owner address is %s\n", owner)
+		owner, err := runner.GetOwner(ctx, client, blockRef)
+		if err != nil {
+			return err
+		}
+		return output.Print(config.App.Output, contractOwner{Contract: config.App.Contract.Address, Owner: owner, Block: blockRef.Number})
 	default:
 		if targetAddress == "" {
 			return ErrInvalidOwnershipAddress
 		}
-		err = runner.TransferOwner(ctx, client, targetAddress)
-		if err != nil {
-			return err
-		}
+		info, err := runner.TransferOwner(ctx, client, targetAddress)
+		return printTransaction(info, targetAddress, 0, err)
 	}
+}
+
+// contractOwner the owner of the contract at a block, the block is nil when it is read at the pending block
+type contractOwner struct {
+	Contract string   `json:"contract"`
+	Owner    string   `json:"owner"`
+	Block    *big.Int `json:"block"`
+}
+
+// Columns returns the owner columns
+func (o contractOwner) Columns() []string {
+	return []string{"contract", "owner", "block"}
+}
+
+// Rows returns the owner row
+func (o contractOwner) Rows() [][]string {
+	return [][]string{{o.Contract, o.Owner, blockName(o.Block)}}
+}
 
-	return nil
+// Text prints the owner
+func (o contractOwner) Text(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Current owner address is %s\n", o.Owner)
+	return err
 }
 
@@ -3,7 +3,6 @@ package api
 import (
 	"context"
 	"errors"
-	"fmt"
 	"github.com/StevenRojas/sharedWallet/config"
 	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -27,11 +26,8 @@ func NewTransferCommand(ctx context.Context) *cobra.Command {
 	transfersCommand := &cobra.Command{
 		Use:   "transfer",
 		Short: "Perform transfer operations",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := runTransfers(ctx, action, targetAddress, amount)
-			if err != nil {
-				fmt.Println("ERROR: " + err.Error())
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTransfers(ctx, action, targetAddress, amount)
 		},
 	}
 
@@ -59,19 +55,16 @@ func runTransfers(ctx context.Context, action string, targetAddress string, amou
 		return ErrInvalidAmountAction
 	}
 
+	var info blockchain.TransactionInfo
 	switch action {
 	case blockchain.SendAction:
 		if targetAddress == "" {
 			return ErrInvalidOwnershipAddress
 		}
-		err = runner.Send(ctx, client, targetAddress, amount)
+		info, err = runner.Send(ctx, client, targetAddress, amount)
 	case blockchain.ReceiveAction:
-		err = runner.Receive(ctx, client, amount)
+		info, err = runner.Receive(ctx, client, amount)
 	}
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return printTransaction(info, targetAddress, amount, err)
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
 	"github.com/spf13/cobra"
 )
 
@@ -13,9 +14,9 @@ func NewRootCommand(ctx context.Context) *cobra.Command {
 		Use:   "wallet",
 		Short: "Run the shared wallet service",
 
-		PersistentPreRunE: config.Setup,
+		PersistentPreRunE: setup,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return errors.New("please specify a command: [deploy, monitor or run]")
+			return errors.New("please specify a command: [deploy, monitor, run, key, events, ledger, report, reconcile, budget, export or snapshot]")
 		},
 	}
 
@@ -28,9 +29,26 @@ func NewRootCommand(ctx context.Context) *cobra.Command {
 	)
 
 	rootCommand.PersistentFlags().StringP("blockchain.pk", "k", "", "Account private key")
+	rootCommand.PersistentFlags().String("output", output.TextFormat, "Output format of the results: text, json, yaml, table or csv")
 	rootCommand.AddCommand(NewDeployCommand(ctx))
 	rootCommand.AddCommand(NewMonitorCommand(ctx))
 	rootCommand.AddCommand(NewRunnerCommand(ctx))
+	rootCommand.AddCommand(NewKeyCommand(ctx))
+	rootCommand.AddCommand(NewEventsCommand(ctx))
+	rootCommand.AddCommand(NewLedgerCommand(ctx))
+	rootCommand.AddCommand(NewReportCommand(ctx))
+	rootCommand.AddCommand(NewReconcileCommand(ctx))
+	rootCommand.AddCommand(NewBudgetCommand(ctx))
+	rootCommand.AddCommand(NewExportCommand(ctx))
+	rootCommand.AddCommand(NewSnapshotCommand(ctx))
 
 	return rootCommand
+}
+
+// setup reads the configuration and checks the output format
+func setup(cmd *cobra.Command, args []string) error {
+	if err := config.Setup(cmd, args); err != nil {
+		return err
+	}
+	return output.Validate(config.App.Output)
 }
\ No newline at end of file
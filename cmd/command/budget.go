@@ -0,0 +1,108 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/cmd/command/api"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/spf13/cobra"
+	"io"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+)
+
+// NewBudgetCommand creates the budget command
+func NewBudgetCommand(ctx context.Context) *cobra.Command {
+	var (
+		block string
+		at    string
+	)
+	budgetCommand := &cobra.Command{
+		Use:   "budget",
+		Short: "Compare the outstanding allowances with the contract balance",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return budget(ctx, block, at)
+		},
+	}
+	budgetCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	budgetCommand.Flags().Float64("budget.limit", 0, "Maximum of the allowances as a ratio of the contract balance, i.e. 1.5")
+	api.AddBlockFlags(budgetCommand, &block, &at)
+	return budgetCommand
+}
+
+func budget(ctx context.Context, block string, at string) error {
+	client, err := dialNode(ctx)
+	if err != nil {
+		return err
+	}
+	blockRef, err := blockchain.ResolveBlock(ctx, client, block, at)
+	if err != nil {
+		return err
+	}
+	b, err := blockchain.ReadBudget(ctx, client, config.App.Contract.Address, blockRef)
+	if err != nil {
+		return err
+	}
+	if b.Beneficiaries == nil {
+		b.Beneficiaries = []blockchain.BeneficiaryAllowance{}
+	}
+	limit := config.App.Budget.Limit
+	return output.Print(config.App.Output, budgetSummary{Budget: b, Limit: limit, OverLimit: b.Exceeds(b.Allowances, limit)})
+}
+
+// budgetSummary the budget printed by the output formats with its limit, the rows are the beneficiaries with the share
+// of the balance committed to them, the text output has the totals before them
+type budgetSummary struct {
+	blockchain.Budget
+	Limit     float64 `json:"limit,omitempty"`
+	OverLimit bool    `json:"over_limit"`
+}
+
+// Columns returns the beneficiary columns
+func (b budgetSummary) Columns() []string {
+	return []string{"beneficiary", "allowance", "share_of_balance"}
+}
+
+// Rows returns the beneficiary rows
+func (b budgetSummary) Rows() [][]string {
+	rows := make([][]string, 0, len(b.Beneficiaries))
+	for _, beneficiary := range b.Beneficiaries {
+		share := "-"
+		if b.Balance.Sign() > 0 {
+			ratio, _ := new(big.Rat).SetFrac(beneficiary.Allowance, b.Balance).Float64()
+			share = fmt.Sprintf("%.2f%%", ratio*100)
+		}
+		rows = append(rows, []string{beneficiary.Beneficiary, blockchain.FormatEther(beneficiary.Allowance), share})
+	}
+	return rows
+}
+
+// Text prints the totals followed by the beneficiaries
+func (b budgetSummary) Text(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(writer, "block\t%d\n", b.Block)
+	_, _ = fmt.Fprintf(writer, "contract balance\t%s\n", blockchain.FormatEther(b.Balance))
+	_, _ = fmt.Fprintf(writer, "outstanding allowances\t%s\t%d beneficiaries\n", blockchain.FormatEther(b.Allowances), len(b.Beneficiaries))
+	coverage := "-"
+	if b.Coverage != nil {
+		coverage = fmt.Sprintf("%.2f%%", *b.Coverage*100)
+	}
+	_, _ = fmt.Fprintf(writer, "coverage\t%s\n", coverage)
+	_, _ = fmt.Fprintf(writer, "over-commitment\t%s\n", blockchain.FormatEther(b.OverCommitment))
+	if b.Limit > 0 {
+		status := "ok"
+		if b.OverLimit {
+			status = "exceeded"
+		}
+		_, _ = fmt.Fprintf(writer, "limit\t%g times the balance\t%s\n", b.Limit, status)
+	}
+	_, _ = fmt.Fprintln(writer)
+	_, _ = fmt.Fprintln(writer, "beneficiary\tallowance\tshare of balance")
+	for _, row := range b.Rows() {
+		_, _ = fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
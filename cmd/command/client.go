@@ -0,0 +1,36 @@
+package command
+
+import (
+	"context"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/price"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+// dialNode dials the node HTTP address, the timeout only applies to the dial since reading the history takes longer
+func dialNode(ctx context.Context) (*ethclient.Client, error) {
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+	return ethclient.DialContext(ctxCall, config.App.Blockchain.Address)
+}
+
+// addPriceFlags adds the flags of the price table used to value the amounts
+func addPriceFlags(cmd *cobra.Command) {
+	cmd.Flags().String("prices.file", "", "CSV file of date and ether price rows used to value the amounts")
+	cmd.Flags().String("prices.currency", "USD", "Currency of the price table")
+}
+
+// loadPrices returns the configured price table, nil when there is no price file
+func loadPrices() (price.Table, error) {
+	if config.App.Prices.File == "" {
+		return nil, nil
+	}
+	return price.Load(config.App.Prices.File)
+}
+
+// valueColumn returns the name of a column valued in the price table currency, i.e. value_usd
+func valueColumn(name string) string {
+	return name + "_" + strings.ToLower(config.App.Prices.Currency)
+}
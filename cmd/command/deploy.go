@@ -2,10 +2,13 @@ package command
 
 import (
 	"context"
+	"fmt"
 	"github.com/StevenRojas/sharedWallet/config"
 	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/spf13/cobra"
+	"io"
 	"log"
 )
 
@@ -34,6 +37,27 @@ func deploy(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	log.Printf("contract deployed at address %s\n", deployer.ContractAddress())
-	return nil
+	return output.Print(config.App.Output, deployment{Contract: deployer.ContractAddress(), TxHash: deployer.TxHash()})
+}
+
+// deployment the deployed contract
+type deployment struct {
+	Contract string `json:"contract"`
+	TxHash   string `json:"tx_hash"`
+}
+
+// Columns returns the deployment columns
+func (d deployment) Columns() []string {
+	return []string{"contract", "tx_hash"}
+}
+
+// Rows returns the deployment row
+func (d deployment) Rows() [][]string {
+	return [][]string{{d.Contract, d.TxHash}}
+}
+
+// Text prints the contract address
+func (d deployment) Text(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "contract deployed at address %s\n", d.Contract)
+	return err
 }
\ No newline at end of file
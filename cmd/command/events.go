@@ -0,0 +1,96 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/StevenRojas/sharedWallet/pkg/store"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	ErrInvalidDate = errors.New("dates should be YYYY, YYYY-MM, YYYY-MM-DD or RFC3339")
+
+	// dateLayouts layouts accepted by the date flags
+	dateLayouts = []string{"2006", "2006-01", "2006-01-02", time.RFC3339}
+)
+
+// NewEventsCommand creates the events command
+func NewEventsCommand(ctx context.Context) *cobra.Command {
+	eventsCommand := &cobra.Command{
+		Use:   "events",
+		Short: "Read the events saved by the monitor in the event store",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errors.New("please specify a subcommand: [query]")
+		},
+	}
+	eventsCommand.AddCommand(newEventsQueryCommand(ctx))
+	return eventsCommand
+}
+
+func newEventsQueryCommand(_ context.Context) *cobra.Command {
+	var (
+		query store.Query
+		since string
+		until string
+	)
+	queryCommand := &cobra.Command{
+		Use:   "query",
+		Short: "Query the stored events by type, beneficiary, sender, date or block",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if query.Type != "" {
+				if _, err = blockchain.NewEventFilter([]string{query.Type}, nil, nil, nil, 0); err != nil {
+					return err
+				}
+			}
+			if query.Since, err = parseDate(since); err != nil {
+				return err
+			}
+			if query.Until, err = parseDate(until); err != nil {
+				return err
+			}
+			return queryEvents(query)
+		},
+	}
+	queryCommand.Flags().String("store.path", "", "Event store file")
+	queryCommand.Flags().StringVar(&query.Contract, "contract", "", "Contract address or label of the events")
+	queryCommand.Flags().StringVar(&query.Type, "type", "", "Event type: AllowanceChanged, MoneySent, MoneyReceived, OwnershipTransferred")
+	queryCommand.Flags().StringVar(&query.Beneficiary, "beneficiary", "", "Beneficiary address of the AllowanceChanged and MoneySent events")
+	queryCommand.Flags().StringVar(&query.Sender, "sender", "", "Sender address of the AllowanceChanged and MoneyReceived events")
+	queryCommand.Flags().StringVar(&since, "since", "", "Events at or after this date, YYYY-MM-DD or RFC3339")
+	queryCommand.Flags().StringVar(&until, "until", "", "Events before this date, YYYY-MM-DD or RFC3339")
+	queryCommand.Flags().Uint64Var(&query.FromBlock, "from-block", 0, "First block of the events")
+	queryCommand.Flags().Uint64Var(&query.ToBlock, "to-block", 0, "Last block of the events, 0 for no limit")
+	queryCommand.Flags().IntVar(&query.Limit, "limit", 0, "Maximum number of events, 0 for no limit")
+	return queryCommand
+}
+
+func queryEvents(query store.Query) error {
+	events, err := store.Open(config.App.Store.Path, true)
+	if err != nil {
+		return err
+	}
+	defer events.Close()
+	result, err := events.Query(query)
+	if err != nil {
+		return err
+	}
+	return output.Print(config.App.Output, store.Events(result))
+}
+
+// parseDate parses a YYYY, YYYY-MM or YYYY-MM-DD date or a RFC3339 time, an empty value returns the zero time
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, ErrInvalidDate
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/journal"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+)
+
+var ErrExportOutput = errors.New("export writes accounting entries, use --format beancount or ledger instead of --output")
+
+// NewExportCommand creates the export command, the global output flag is refused since the entries have their own format
+func NewExportCommand(ctx context.Context) *cobra.Command {
+	var (
+		format    string
+		gas       bool
+		fromBlock uint64
+		commodity string
+		output    string
+	)
+	exportCommand := &cobra.Command{
+		Use:   "export",
+		Short: "Export the money received and sent as plain-text accounting entries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("output") {
+				return ErrExportOutput
+			}
+			return export(ctx, format, gas, fromBlock, commodity, output)
+		},
+	}
+	exportCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	exportCommand.Flags().StringVar(&format, "format", journal.BeancountFormat, "Output format: beancount or ledger")
+	exportCommand.Flags().BoolVar(&gas, "gas", false, "Post the gas fees of the transactions read from their receipts")
+	exportCommand.Flags().Uint64Var(&fromBlock, "from-block", 0, "First block of the events")
+	exportCommand.Flags().StringVar(&commodity, "commodity", "ETH", "Commodity of the amounts")
+	exportCommand.Flags().StringVarP(&output, "output-file", "o", "", "File where the entries are written instead of the standard output")
+	return exportCommand
+}
+
+func export(ctx context.Context, format string, gas bool, fromBlock uint64, commodity string, output string) error {
+	if format != journal.BeancountFormat && format != journal.LedgerFormat {
+		return journal.ErrInvalidFormat
+	}
+	client, err := dialNode(ctx)
+	if err != nil {
+		return err
+	}
+	history, err := blockchain.NewHistory(client, config.App.Contract.Address)
+	if err != nil {
+		return err
+	}
+	records, err := history.Records(ctx, blockchain.HistoryQuery{
+		Types:     []string{blockchain.MoneyReceived, blockchain.MoneySent},
+		FromBlock: fromBlock,
+	})
+	if err != nil {
+		return err
+	}
+	var costs map[string]blockchain.GasCost
+	if gas {
+		txHashes := make([]string, 0, len(records))
+		for _, record := range records {
+			txHashes = append(txHashes, record.TxHash)
+		}
+		if costs, err = blockchain.GasCosts(ctx, client, txHashes); err != nil {
+			return err
+		}
+	}
+	entries := journal.NewJournal(config.App.Contract.Address, records, costs, journal.Accounts{Labels: accountLabels()})
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return journal.Write(w, entries, format, commodity)
+}
+
+// accountLabels returns the configured address labels with the labels of the monitored contracts
+func accountLabels() map[string]string {
+	labels := map[string]string{}
+	for address, label := range config.App.Labels {
+		labels[address] = label
+	}
+	for _, contract := range config.App.Monitor.Contracts {
+		if contract.Label != "" {
+			labels[contract.Address] = contract.Label
+		}
+	}
+	return labels
+}
@@ -0,0 +1,251 @@
+package command
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/StevenRojas/sharedWallet/pkg/shamir"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrMissingShares = errors.New("please specify the share files or words to combine")
+
+// NewKeyCommand creates the key command
+func NewKeyCommand(ctx context.Context) *cobra.Command {
+	keyCommand := &cobra.Command{
+		Use:   "key",
+		Short: "Split or recover the owner private key using Shamir secret sharing",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errors.New("please specify a subcommand: [split or combine]")
+		},
+	}
+	keyCommand.AddCommand(newKeySplitCommand())
+	keyCommand.AddCommand(newKeyCombineCommand(ctx))
+	return keyCommand
+}
+
+func newKeySplitCommand() *cobra.Command {
+	var (
+		shares     int
+		threshold  int
+		outputDir  string
+		keystore   string
+		passphrase string
+	)
+	splitCommand := &cobra.Command{
+		Use:   "split",
+		Short: "Split the owner private key into shares",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return splitKey(shares, threshold, outputDir, keystore, passphrase)
+		},
+	}
+	splitCommand.Flags().IntVar(&shares, "shares", 5, "Number of shares to generate")
+	splitCommand.Flags().IntVar(&threshold, "threshold", 3, "Number of shares required to recover the key")
+	splitCommand.Flags().StringVarP(&outputDir, "output-dir", "o", "", "Folder where the share files are written, the shares are printed as words if empty")
+	splitCommand.Flags().StringVar(&keystore, "keystore", "", "Keystore file holding the private key instead of the configured one")
+	splitCommand.Flags().StringVar(&passphrase, "passphrase", "", "Keystore passphrase")
+	return splitCommand
+}
+
+func newKeyCombineCommand(ctx context.Context) *cobra.Command {
+	var (
+		words      []string
+		outputFile string
+		verify     bool
+	)
+	combineCommand := &cobra.Command{
+		Use:   "combine [share files]",
+		Short: "Recover the owner private key from its shares",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return combineKey(ctx, args, words, outputFile, verify)
+		},
+	}
+	combineCommand.Flags().StringArrayVar(&words, "words", nil, "Share printed as words, could be repeated")
+	combineCommand.Flags().StringVarP(&outputFile, "output-file", "o", "", "File where the recovered private key is written, it is printed if empty")
+	combineCommand.Flags().BoolVar(&verify, "verify", true, "Verify the recovered key belongs to the contract owner")
+	combineCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	return combineCommand
+}
+
+func splitKey(shares int, threshold int, outputDir string, keystore string, passphrase string) error {
+	privateKey := config.App.Blockchain.PrivateKey
+	if keystore != "" {
+		var err error
+		privateKey, err = blockchain.KeystorePrivateKey(keystore, passphrase)
+		if err != nil {
+			return err
+		}
+	}
+	secret, err := blockchain.PrivateKeyBytes(privateKey)
+	if err != nil {
+		return err
+	}
+	address, err := blockchain.KeyAddress(secret)
+	if err != nil {
+		return err
+	}
+	parts, err := shamir.Split(secret, shares, threshold)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("key of %s split into %d shares, %d of them are required to recover it\n", address, shares, threshold)
+	result := keyShares{Address: address, Threshold: threshold, Shares: make([]keyShare, len(parts))}
+	if outputDir != "" {
+		if err = os.MkdirAll(outputDir, 0700); err != nil {
+			return err
+		}
+	}
+	for i, part := range parts {
+		result.Shares[i].Share = i + 1
+		if outputDir == "" {
+			result.Shares[i].Words = shamir.ToWords(part)
+			continue
+		}
+		result.Shares[i].File = filepath.Join(outputDir, fmt.Sprintf("share-%d.txt", i+1))
+		if err = ioutil.WriteFile(result.Shares[i].File, []byte(hex.EncodeToString(part)+"\n"), 0600); err != nil {
+			return err
+		}
+	}
+	return output.Print(config.App.Output, result)
+}
+
+func combineKey(ctx context.Context, files []string, words []string, outputFile string, verify bool) error {
+	if len(files)+len(words) == 0 {
+		return ErrMissingShares
+	}
+	parts := make([][]byte, 0, len(files)+len(words))
+	for _, filename := range files {
+		content, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		part, err := hex.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, shamir.ErrInvalidShare)
+		}
+		parts = append(parts, part)
+	}
+	for _, word := range words {
+		part, err := shamir.FromWords(word)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, part)
+	}
+
+	secret, err := shamir.Combine(parts)
+	if err != nil {
+		return err
+	}
+	address, err := blockchain.KeyAddress(secret)
+	if err != nil {
+		return err
+	}
+	if verify {
+		ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+		defer cancel()
+		client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.Address)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		if _, err = blockchain.VerifyOwnerKey(ctx, client, config.App.Contract.Address, secret); err != nil {
+			return err
+		}
+		log.Printf("recovered key matches the contract owner %s\n", address)
+	} else {
+		log.Printf("recovered key of %s\n", address)
+	}
+
+	result := recoveredKey{Address: address, Verified: verify}
+	if outputFile == "" {
+		result.PrivateKey = hex.EncodeToString(secret)
+	} else {
+		if err = ioutil.WriteFile(outputFile, []byte(hex.EncodeToString(secret)+"\n"), 0600); err != nil {
+			return err
+		}
+		result.File = outputFile
+	}
+	return output.Print(config.App.Output, result)
+}
+
+// keyShare a share printed as words or written into a file
+type keyShare struct {
+	Share int    `json:"share"`
+	Words string `json:"words,omitempty"`
+	File  string `json:"file,omitempty"`
+}
+
+// keyShares the shares of a split key printed by the output formats
+type keyShares struct {
+	Address   string     `json:"address"`
+	Threshold int        `json:"threshold"`
+	Shares    []keyShare `json:"shares"`
+}
+
+// Columns returns the share columns
+func (k keyShares) Columns() []string {
+	return []string{"share", "words", "file"}
+}
+
+// Rows returns the share rows
+func (k keyShares) Rows() [][]string {
+	rows := make([][]string, 0, len(k.Shares))
+	for _, share := range k.Shares {
+		rows = append(rows, []string{strconv.Itoa(share.Share), share.Words, share.File})
+	}
+	return rows
+}
+
+// Text prints the share words or the files they were written to
+func (k keyShares) Text(w io.Writer) error {
+	for _, share := range k.Shares {
+		if share.File != "" {
+			_, _ = fmt.Fprintf(w, "share %d written to %s\n", share.Share, share.File)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "share %d: %s\n", share.Share, share.Words)
+	}
+	return nil
+}
+
+// recoveredKey the key combined from its shares, the private key is not set when it is written into a file
+type recoveredKey struct {
+	Address    string `json:"address"`
+	PrivateKey string `json:"private_key,omitempty"`
+	File       string `json:"file,omitempty"`
+	Verified   bool   `json:"verified"`
+}
+
+// Columns returns the key columns
+func (k recoveredKey) Columns() []string {
+	return []string{"address", "private_key", "file", "verified"}
+}
+
+// Rows returns the key row
+func (k recoveredKey) Rows() [][]string {
+	return [][]string{{k.Address, k.PrivateKey, k.File, strconv.FormatBool(k.Verified)}}
+}
+
+// Text prints the private key, or the file it was written to
+func (k recoveredKey) Text(w io.Writer) error {
+	if k.File != "" {
+		_, err := fmt.Fprintf(w, "private key of %s written to %s\n", k.Address, k.File)
+		return err
+	}
+	_, err := fmt.Fprintln(w, k.PrivateKey)
+	return err
+}
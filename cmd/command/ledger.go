@@ -0,0 +1,141 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/cmd/command/api"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var ledgerColumns = []string{"time", "block", "tx_hash", "kind", "amount", "allowance"}
+
+// NewLedgerCommand creates the ledger command
+func NewLedgerCommand(ctx context.Context) *cobra.Command {
+	var (
+		fromBlock uint64
+		block     string
+		at        string
+	)
+	ledgerCommand := &cobra.Command{
+		Use:   "ledger <beneficiary>",
+		Short: "Print the statement of a beneficiary from the contract events",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ledger(ctx, args[0], fromBlock, block, at)
+		},
+	}
+	ledgerCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	ledgerCommand.Flags().Uint64Var(&fromBlock, "from-block", 0, "First block of the events")
+	addPriceFlags(ledgerCommand)
+	api.AddBlockFlags(ledgerCommand, &block, &at)
+	return ledgerCommand
+}
+
+func ledger(ctx context.Context, beneficiary string, fromBlock uint64, block string, at string) error {
+	if !common.IsHexAddress(beneficiary) {
+		return blockchain.ErrInvalidAddress
+	}
+	client, err := dialNode(ctx)
+	if err != nil {
+		return err
+	}
+	blockRef, err := blockchain.ResolveBlock(ctx, client, block, at)
+	if err != nil {
+		return err
+	}
+	toBlock, err := blockRef.Resolve(ctx, client)
+	if err != nil {
+		return err
+	}
+	lastBlock := toBlock.Uint64()
+	history, err := blockchain.NewHistory(client, config.App.Contract.Address)
+	if err != nil {
+		return err
+	}
+	records, err := history.Records(ctx, blockchain.HistoryQuery{
+		Types:         []string{blockchain.AllowanceChanged, blockchain.MoneySent},
+		Beneficiaries: []string{beneficiary},
+		FromBlock:     fromBlock,
+		ToBlock:       &lastBlock,
+	})
+	if err != nil {
+		return err
+	}
+	statement := blockchain.NewLedger(beneficiary, records)
+	prices, err := loadPrices()
+	if err != nil {
+		return err
+	}
+	if prices != nil {
+		if err = statement.Valuate(prices); err != nil {
+			return err
+		}
+	}
+	return output.Print(config.App.Output, ledgerStatement{statement})
+}
+
+// ledgerStatement the ledger printed by the output formats, the text output has the totals after the entries
+type ledgerStatement struct {
+	blockchain.Ledger
+}
+
+// Columns returns the entry columns, the valued statements have a value column
+func (s ledgerStatement) Columns() []string {
+	if s.PaidOutValue != nil {
+		return append(ledgerColumns[:len(ledgerColumns):len(ledgerColumns)], valueColumn("value"))
+	}
+	return ledgerColumns
+}
+
+// Rows returns the entry rows
+func (s ledgerStatement) Rows() [][]string {
+	rows := make([][]string, 0, len(s.Entries))
+	for _, entry := range s.Entries {
+		rows = append(rows, ledgerRow(entry))
+	}
+	return rows
+}
+
+// Text prints the entries followed by the totals
+func (s ledgerStatement) Text(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(writer, "Ledger of %s\n\n", s.Beneficiary)
+	_, _ = fmt.Fprintln(writer, strings.Join(s.Columns(), "\t"))
+	for _, row := range s.Rows() {
+		_, _ = fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	_, _ = fmt.Fprintln(writer)
+	_, _ = fmt.Fprintf(writer, "granted\t%s\n", blockchain.FormatEther(s.Granted))
+	_, _ = fmt.Fprintf(writer, "reduced\t%s\n", blockchain.FormatEther(s.Reduced))
+	_, _ = fmt.Fprintf(writer, "paid out\t%s\n", blockchain.FormatEther(s.PaidOut))
+	if s.PaidOutValue != nil {
+		_, _ = fmt.Fprintf(writer, "paid out value\t%s %s\n", s.PaidOutValue, config.App.Prices.Currency)
+	}
+	_, _ = fmt.Fprintf(writer, "allowance\t%s\n", blockchain.FormatEther(s.Allowance))
+	return writer.Flush()
+}
+
+// ledgerRow returns the entry columns values, the value column is added when the entry is valued
+func ledgerRow(entry blockchain.LedgerEntry) []string {
+	row := []string{
+		entry.Time.UTC().Format(time.RFC3339),
+		strconv.FormatUint(entry.Block, 10),
+		entry.TxHash,
+		entry.Kind,
+		blockchain.FormatEther(entry.Amount),
+		blockchain.FormatEther(entry.Allowance),
+	}
+	if entry.Value != nil {
+		row = append(row, entry.Value.String())
+	}
+	return row
+}
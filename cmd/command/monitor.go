@@ -2,36 +2,276 @@ package command
 
 import (
 	"context"
+	"errors"
 	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/alert"
 	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/metrics"
+	"github.com/StevenRojas/sharedWallet/pkg/sink"
+	"github.com/StevenRojas/sharedWallet/pkg/store"
+	"github.com/StevenRojas/sharedWallet/pkg/stream"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/spf13/cobra"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	earliestBlock = "earliest"
+	latestBlock = "latest"
+	defaultPollInterval = 5 * time.Second
+	defaultMetricsRefresh = 30 * time.Second
+)
+
+var (
+	ErrInvalidFromBlock = errors.New("from block should be earliest, latest or a block number")
+	ErrMonitorsFailed = errors.New("every contract monitor failed")
 )
 
 // NewMonitorCommand creates the monitor command
 func NewMonitorCommand(ctx context.Context) *cobra.Command {
+	var (
+		fromBlock string
+		pageSize uint64
+		confirmations uint64
+		events []string
+		beneficiaries []string
+		senders []string
+		from []string
+		minAmount int64
+		pollInterval time.Duration
+		metricsAddr string
+		metricsRefresh time.Duration
+		contracts []string
+		sseAddr string
+		grpcAddr string
+	)
 	monitorCommand := &cobra.Command{
 		Use:   "monitor",
 		Short: "Monitor events in the blockchain",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return monitoring(ctx)
+			filter, err := blockchain.NewEventFilter(events, beneficiaries, senders, from, minAmount)
+			if err != nil {
+				return err
+			}
+			options := blockchain.MonitorOptions{
+				PageSize: pageSize,
+				Confirmations: confirmations,
+				Filter: filter,
+			}
+			if metricsAddr != "" {
+				options.MetricsRefresh = metricsRefresh
+			}
+			servers := monitorServers{metrics: metricsAddr, sse: sseAddr, grpc: grpcAddr}
+			// the events are printed as JSON unless the output flag is set
+			stdoutFormat := ""
+			if cmd.Flags().Changed("output") {
+				stdoutFormat = config.App.Output
+			}
+			return monitoring(ctx, options, monitoredContracts(contracts), fromBlock, pollInterval, servers, stdoutFormat)
 		},
 	}
 	monitorCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	monitorCommand.Flags().StringSliceVar(&contracts, "contracts", nil, "Contracts to monitor instead of the contract address, as address or label=address")
+	monitorCommand.Flags().String("monitor.checkpoint", "", "File where the last processed events are saved to resume from them")
+	monitorCommand.Flags().String("monitor.rules", "", "Alert rules file evaluated on the events and the contract state")
+	monitorCommand.Flags().String("store.path", "", "Event store file where every emitted event is saved")
+	monitorCommand.Flags().StringVar(&fromBlock, "from-block", earliestBlock, "Block to replay events from: earliest, latest or a block number")
+	monitorCommand.Flags().Uint64Var(&confirmations, "confirmations", 0, "Number of blocks mined on top of an event block before emitting it")
+	monitorCommand.Flags().Uint64Var(&pageSize, "page-size", blockchain.DefaultPageSize, "Number of blocks requested on every history page")
+	monitorCommand.Flags().StringSliceVar(&events, "events", nil, "Event types to watch: AllowanceChanged, MoneySent, MoneyReceived, OwnershipTransferred")
+	monitorCommand.Flags().StringSliceVar(&beneficiaries, "beneficiary", nil, "Beneficiary addresses of the AllowanceChanged and MoneySent events")
+	monitorCommand.Flags().StringSliceVar(&senders, "sender", nil, "Sender addresses of the AllowanceChanged events")
+	monitorCommand.Flags().StringSliceVar(&from, "from", nil, "Sender addresses of the MoneyReceived events")
+	monitorCommand.Flags().DurationVar(&pollInterval, "poll-interval", defaultPollInterval, "Time between two log requests when there is no WebSocket address and the logs are polled")
+	monitorCommand.Flags().Int64Var(&minAmount, "min-amount", 0, "Minimum amount of the MoneySent and MoneyReceived events")
+	monitorCommand.Flags().StringVar(&metricsAddr, "metrics-addr", "", "Address where the Prometheus metrics are served, i.e. :9100")
+	monitorCommand.Flags().StringVar(&sseAddr, "sse-addr", "", "Address where the events are streamed as Server-Sent Events in /events, i.e. :8080")
+	monitorCommand.Flags().StringVar(&grpcAddr, "grpc-addr", "", "Address where the events are streamed by the gRPC wallet.v1.Events service, i.e. :9090")
+	monitorCommand.Flags().DurationVar(&metricsRefresh, "metrics-refresh", defaultMetricsRefresh, "Time between two refreshes of the lag, balance and allowance metrics")
 	return monitorCommand
 }
 
-func monitoring(ctx context.Context) error {
-	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
-	defer cancel()
-	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.WS)
+// monitorServers addresses of the servers started by the monitor, empty if they are not started
+type monitorServers struct {
+	metrics string
+	sse string
+	grpc string
+}
+
+func monitoring(ctx context.Context, options blockchain.MonitorOptions, contracts []config.MonitoredContract, fromBlock string, pollInterval time.Duration, servers monitorServers, stdoutFormat string) error {
+	options.Endpoint = config.App.Blockchain.WS
+	if options.Endpoint == "" {
+		log.Printf("there is no WebSocket address, polling the logs every %s\n", pollInterval)
+		options.Endpoint = config.App.Blockchain.Address
+		options.PollInterval = pollInterval
+	}
+	switch fromBlock {
+	case earliestBlock:
+		options.FromBlock = new(uint64)
+	case latestBlock:
+	default:
+		block, err := strconv.ParseUint(fromBlock, 10, 64)
+		if err != nil {
+			return ErrInvalidFromBlock
+		}
+		options.FromBlock = &block
+	}
+
+	sinks, err := sink.NewFanout(config.App.Monitor.Sinks, stdoutFormat)
 	if err != nil {
 		return err
 	}
-	monitor := blockchain.NewMonitor(config.App.Contract.Address)
-	err = monitor.Start(ctx, client)
+	options.Sink = sinks
+	onShutdown(sinks.Close)
+	var events store.Store
+	if config.App.Store.Path != "" {
+		if events, err = store.Open(config.App.Store.Path, false); err != nil {
+			return err
+		}
+		sinks.Add("store", events, true)
+	}
+	if servers.sse != "" || servers.grpc != "" {
+		hub := stream.NewHub(stream.DefaultBufferSize, events)
+		sinks.Add("stream", hub, false)
+		serve(servers.sse, "Server-Sent Events", func(addr string) error {
+			return stream.ServeSSE(ctx, addr, hub)
+		})
+		serve(servers.grpc, "gRPC", func(addr string) error {
+			return stream.ServeGRPC(ctx, addr, hub)
+		})
+	}
+	checkpoint, err := blockchain.NewCheckpoint(config.App.Monitor.Checkpoint)
 	if err != nil {
 		return err
 	}
+	options.Checkpoint = checkpoint
+	onShutdown(checkpoint.Flush)
+
+	serve(servers.metrics, "metrics", func(addr string) error {
+		return metrics.Serve(ctx, addr)
+	})
+
+	engines := make([]alert.Engine, len(contracts))
+	if config.App.Monitor.Rules != "" {
+		alerts, err := config.LoadAlerts(config.App.Monitor.Rules)
+		if err != nil {
+			return err
+		}
+		for i, contract := range contracts {
+			if engines[i], err = newAlertEngine(ctx, alerts, contract); err != nil {
+				return err
+			}
+			sinks.Add("alerts", engines[i], false)
+		}
+	}
+
+	// every contract has its own connection so a failing contract does not stop the others
+	errs := make(chan error, len(contracts))
+	for i, contract := range contracts {
+		go func(contract config.MonitoredContract, engine alert.Engine) {
+			ctxContract, cancel := context.WithCancel(ctx)
+			defer cancel()
+			if engine != nil {
+				go func() {
+					_ = engine.Run(ctxContract)
+				}()
+			}
+			contractOptions := options
+			contractOptions.Label = contract.Label
+			err := monitorContract(ctxContract, contract, contractOptions)
+			if err != nil {
+				log.Printf("%s monitor stopped: %s\n", contractName(contract), err.Error())
+			}
+			errs <- err
+		}(contract, engines[i])
+	}
+	failed := 0
+	var lastErr error
+	for range contracts {
+		if err := <-errs; err != nil {
+			failed++
+			lastErr = err
+		}
+	}
+	if len(contracts) == 1 {
+		return lastErr
+	}
+	if failed == len(contracts) {
+		return ErrMonitorsFailed
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// monitorContract dials a connection and watches the events of a contract
+func monitorContract(ctx context.Context, contract config.MonitoredContract, options blockchain.MonitorOptions) error {
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+	client, err := ethclient.DialContext(ctxCall, options.Endpoint)
+	if err != nil {
+		return err
+	}
+	return blockchain.NewMonitor(contract.Address, options).Start(ctx, client)
+}
+
+// monitoredContracts returns the contracts of the flag, as address or label=address, or the configured ones.
+// The contract address is used when there is no list
+func monitoredContracts(values []string) []config.MonitoredContract {
+	var contracts []config.MonitoredContract
+	for _, value := range values {
+		contract := config.MonitoredContract{Address: value}
+		if i := strings.LastIndex(value, "="); i >= 0 {
+			contract = config.MonitoredContract{Label: value[:i], Address: value[i+1:]}
+		}
+		contracts = append(contracts, contract)
+	}
+	if len(contracts) > 0 {
+		return contracts
+	}
+	if len(config.App.Monitor.Contracts) > 0 {
+		return config.App.Monitor.Contracts
+	}
+	return []config.MonitoredContract{{Address: config.App.Contract.Address}}
+}
+
+// contractName returns the contract address with its label
+func contractName(contract config.MonitoredContract) string {
+	if contract.Label == "" {
+		return contract.Address
+	}
+	return contract.Label + " (" + contract.Address + ")"
+}
+
+// newAlertEngine creates the alert engine of a contract, its state is read through the HTTP address so it is not affected by
+// the subscription reconnections
+func newAlertEngine(ctx context.Context, alerts config.AlertsConfig, contract config.MonitoredContract) (alert.Engine, error) {
+	ctxCall, cancel := context.WithTimeout(ctx, config.App.Blockchain.TimeoutIn)
+	defer cancel()
+	client, err := ethclient.DialContext(ctxCall, config.App.Blockchain.Address)
+	if err != nil {
+		return nil, err
+	}
+	state, err := blockchain.NewContractState(client, contract.Address)
+	if err != nil {
+		return nil, err
+	}
+	head, err := client.BlockNumber(ctxCall)
+	if err != nil {
+		return nil, err
+	}
+	return alert.NewEngine(alerts, contract.Address, contract.Label, state, head)
+}
+
+// serve starts a server when its address is set, the errors are logged since the monitor keeps running without it
+func serve(addr string, name string, start func(addr string) error) {
+	if addr == "" {
+		return
+	}
+	go func() {
+		if err := start(addr); err != nil {
+			log.Printf("unable to serve %s: %s\n", name, err.Error())
+		}
+	}()
+}
@@ -0,0 +1,134 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/cmd/command/api"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/spf13/cobra"
+	"io"
+	"math/big"
+	"strconv"
+)
+
+var ErrDrift = errors.New("the contract state drifted from its events")
+
+var reconcileColumns = []string{"kind", "subject", "expected", "actual", "difference", "status"}
+
+// NewReconcileCommand creates the reconcile command
+func NewReconcileCommand(ctx context.Context) *cobra.Command {
+	var (
+		block string
+		at    string
+	)
+	reconcileCommand := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compare the balance and allowances derived from the events with the contract state",
+		// a drift is reported as an error to exit with a non-zero status, it is not a usage error
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reconcile(ctx, block, at)
+		},
+	}
+	reconcileCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	api.AddBlockFlags(reconcileCommand, &block, &at)
+	return reconcileCommand
+}
+
+func reconcile(ctx context.Context, block string, at string) error {
+	client, err := dialNode(ctx)
+	if err != nil {
+		return err
+	}
+	blockRef, err := blockchain.ResolveBlock(ctx, client, block, at)
+	if err != nil {
+		return err
+	}
+	r, err := blockchain.Reconcile(ctx, client, config.App.Contract.Address, blockRef)
+	if err != nil {
+		return err
+	}
+	if r.Drifts == nil {
+		r.Drifts = []blockchain.Drift{}
+	}
+	if err = output.Print(config.App.Output, reconciliation{r}); err != nil {
+		return err
+	}
+	if r.Drifted() {
+		return ErrDrift
+	}
+	return nil
+}
+
+// reconciliation the reconciliation printed by the output formats, the rows are the checks followed by the allowance
+// gaps, the text output has the gaps as sentences after the checks
+type reconciliation struct {
+	blockchain.Reconciliation
+}
+
+// Columns returns the reconciliation columns
+func (r reconciliation) Columns() []string {
+	return reconcileColumns
+}
+
+// Rows returns the rows of the checks with their status followed by the allowance gaps
+func (r reconciliation) Rows() [][]string {
+	rows := r.checks()
+	for _, gap := range r.gaps() {
+		rows = append(rows, []string{gap.Kind, gap.Subject, blockchain.FormatEther(gap.Expected), blockchain.FormatEther(gap.Actual),
+			blockchain.FormatEther(new(big.Int).Sub(gap.Actual, gap.Expected)), "gap"})
+	}
+	return rows
+}
+
+// Text prints the checks followed by the allowance gaps
+func (r reconciliation) Text(w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "%d events replayed up to block %d\n\n", r.Events, r.Block)
+	if err := output.WriteTable(w, r.Columns(), r.checks()); err != nil {
+		return err
+	}
+	gaps := r.gaps()
+	for _, gap := range gaps {
+		_, _ = fmt.Fprintf(w, "\nmissing allowance change of %s before block %s (%s): previous amount %s, expected %s", gap.Subject,
+			strconv.FormatUint(gap.Block, 10), gap.TxHash, blockchain.FormatEther(gap.Actual), blockchain.FormatEther(gap.Expected))
+	}
+	if len(gaps) > 0 {
+		_, _ = fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// checks returns the balance and allowance checks rows with their status
+func (r reconciliation) checks() [][]string {
+	drifted := map[string]bool{}
+	for _, drift := range r.Drifts {
+		drifted[drift.Kind+drift.Subject] = true
+	}
+	row := func(kind string, subject string, expected *big.Int, actual *big.Int) []string {
+		status := "ok"
+		if drifted[kind+subject] {
+			status = "drift"
+		}
+		return []string{kind, subject, blockchain.FormatEther(expected), blockchain.FormatEther(actual),
+			blockchain.FormatEther(new(big.Int).Sub(actual, expected)), status}
+	}
+	rows := [][]string{row(blockchain.BalanceDrift, r.Contract, r.ExpectedBalance, r.ActualBalance)}
+	for _, check := range r.Allowances {
+		rows = append(rows, row(blockchain.AllowanceDrift, check.Beneficiary, check.Expected, check.Actual))
+	}
+	return rows
+}
+
+// gaps returns the allowance gaps
+func (r reconciliation) gaps() []blockchain.Drift {
+	var gaps []blockchain.Drift
+	for _, drift := range r.Drifts {
+		if drift.Kind == blockchain.AllowanceGap {
+			gaps = append(gaps, drift)
+		}
+	}
+	return gaps
+}
@@ -0,0 +1,142 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/spf13/cobra"
+	"math/big"
+	"time"
+)
+
+var reportColumns = []string{"period", "party", "address", "opening_balance", "inflows", "outflows", "net_change",
+	"closing_balance", "allowance_granted", "allowance_spent"}
+
+// NewReportCommand creates the report command
+func NewReportCommand(ctx context.Context) *cobra.Command {
+	var (
+		period string
+		from   string
+		to     string
+	)
+	reportCommand := &cobra.Command{
+		Use:   "report",
+		Short: "Aggregate the money sent and received by period",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromTime, err := parseDate(from)
+			if err != nil {
+				return err
+			}
+			toTime, err := parseDate(to)
+			if err != nil {
+				return err
+			}
+			return report(ctx, period, fromTime, toTime)
+		},
+	}
+	reportCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	reportCommand.Flags().StringVar(&period, "period", blockchain.MonthlyPeriod, "Period: daily, weekly, monthly, quarterly or yearly")
+	reportCommand.Flags().StringVar(&from, "from", "", "First period, i.e. 2026-01, the period of the first event if empty")
+	reportCommand.Flags().StringVar(&to, "to", "", "Last period, i.e. 2026-06, the current period if empty")
+	addPriceFlags(reportCommand)
+	return reportCommand
+}
+
+func report(ctx context.Context, period string, from time.Time, to time.Time) error {
+	if _, err := blockchain.PeriodStart(period, from); err != nil {
+		return err
+	}
+	prices, err := loadPrices()
+	if err != nil {
+		return err
+	}
+	client, err := dialNode(ctx)
+	if err != nil {
+		return err
+	}
+	history, err := blockchain.NewHistory(client, config.App.Contract.Address)
+	if err != nil {
+		return err
+	}
+	// the whole history is read since the opening balance is derived from the previous events
+	records, err := history.Records(ctx, blockchain.HistoryQuery{
+		Types: []string{blockchain.AllowanceChanged, blockchain.MoneySent, blockchain.MoneyReceived},
+	})
+	if err != nil {
+		return err
+	}
+	if from.IsZero() {
+		from = time.Now()
+		if len(records) > 0 {
+			from = records[0].Timestamp
+		}
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	periods, err := blockchain.NewReport(records, period, from, to, prices)
+	if err != nil {
+		return err
+	}
+	return output.Print(config.App.Output, reportPeriods(periods))
+}
+
+// reportPeriods the report printed by the output formats, a summary row by period followed by its beneficiaries and
+// funders
+type reportPeriods []blockchain.ReportPeriod
+
+// Columns returns the report columns, the valued periods have the value columns
+func (r reportPeriods) Columns() []string {
+	if len(r) == 0 || r[0].Fiat == nil {
+		return reportColumns
+	}
+	return append(reportColumns[:len(reportColumns):len(reportColumns)], valueColumn("opening_value"), valueColumn("inflows_value"),
+		valueColumn("outflows_value"), valueColumn("net_change_value"), valueColumn("closing_value"), valueColumn("revaluation"))
+}
+
+// Rows returns the period, beneficiary and funder rows padded to the column count
+func (r reportPeriods) Rows() [][]string {
+	var rows [][]string
+	for _, p := range r {
+		row := []string{p.Period, "contract", config.App.Contract.Address, ether(p.OpeningBalance),
+			ether(p.Inflows), ether(p.Outflows), ether(p.NetChange), ether(p.ClosingBalance),
+			ether(p.AllowanceGranted), ether(p.AllowanceSpent)}
+		if p.Fiat != nil {
+			row = append(row, p.Fiat.OpeningBalance.String(), p.Fiat.Inflows.String(), p.Fiat.Outflows.String(),
+				p.Fiat.NetChange.String(), p.Fiat.ClosingBalance.String(), p.Fiat.Revaluation.String())
+		}
+		rows = append(rows, row)
+		for _, b := range p.Beneficiaries {
+			rows = append(rows, []string{p.Period, "beneficiary", b.Address, "", "", ether(b.Outflows),
+				"", "", ether(b.AllowanceGranted), ether(b.Outflows)})
+		}
+		for _, f := range p.Funders {
+			rows = append(rows, []string{p.Period, "funder", f.Address, "", ether(f.Inflows), "", "", "", "", ""})
+		}
+	}
+	columns := len(r.Columns())
+	for i := range rows {
+		for len(rows[i]) < columns {
+			rows[i] = append(rows[i], "")
+		}
+	}
+	return rows
+}
+
+// MarshalJSON returns the periods, an empty report is an empty array
+func (r reportPeriods) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]blockchain.ReportPeriod(r))
+}
+
+// ether formats a wei amount as ether, nil amounts are blank
+func ether(wei *big.Int) string {
+	if wei == nil {
+		return ""
+	}
+	return blockchain.FormatEther(wei)
+}
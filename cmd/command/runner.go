@@ -4,11 +4,17 @@ import (
 	"context"
 	"errors"
 	"github.com/StevenRojas/sharedWallet/cmd/command/api"
+	"github.com/StevenRojas/sharedWallet/pkg/metrics"
 	"github.com/spf13/cobra"
+	"log"
 )
 
+// runnerJob Pushgateway job of the runner metrics
+const runnerJob = "wallet_runner"
+
 // NewRunnerCommand creates the runner command
 func NewRunnerCommand(ctx context.Context) *cobra.Command {
+	var metricsPush string
 	runCommand := &cobra.Command{
 		Use:   "run",
 		Short: "Run contract methods in the blockchain",
@@ -16,10 +22,29 @@ func NewRunnerCommand(ctx context.Context) *cobra.Command {
 			return errors.New("please specify a subcommand: [allowance, balance, ownership or transfer]")
 		},
 	}
+	runCommand.PersistentFlags().StringVar(&metricsPush, "metrics-push", "", "Prometheus Pushgateway URL where the transaction metrics are pushed")
 
 	runCommand.AddCommand(api.NewAllowanceCommand(ctx))
 	runCommand.AddCommand(api.NewTransferCommand(ctx))
 	runCommand.AddCommand(api.NewBalanceCommand(ctx))
 	runCommand.AddCommand(api.NewOwnershipCommand(ctx))
+	// the metrics are pushed after every subcommand, the failed ones included
+	for _, command := range runCommand.Commands() {
+		run := command.RunE
+		command.RunE = func(cmd *cobra.Command, args []string) error {
+			defer pushMetrics(metricsPush)
+			return run(cmd, args)
+		}
+	}
 	return runCommand
+}
+
+// pushMetrics pushes the runner metrics when the Pushgateway URL is set
+func pushMetrics(url string) {
+	if url == "" {
+		return
+	}
+	if err := metrics.Push(url, runnerJob); err != nil {
+		log.Printf("unable to push metrics: %s\n", err.Error())
+	}
 }
\ No newline at end of file
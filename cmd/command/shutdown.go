@@ -0,0 +1,31 @@
+package command
+
+import (
+	"log"
+	"sync"
+)
+
+var (
+	shutdownMutex sync.Mutex
+	// shutdownHooks functions executed when the application is closing
+	shutdownHooks []func() error
+)
+
+// onShutdown register a function to be executed when the application is closing
+func onShutdown(hook func() error) {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
+// Shutdown executes the registered hooks, every hook is executed only once
+func Shutdown() {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+	for _, hook := range shutdownHooks {
+		if err := hook(); err != nil {
+			log.Printf("shutdown error: %s\n", err.Error())
+		}
+	}
+	shutdownHooks = nil
+}
@@ -0,0 +1,175 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/cmd/command/api"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"time"
+)
+
+// NewSnapshotCommand creates the snapshot command
+func NewSnapshotCommand(ctx context.Context) *cobra.Command {
+	var (
+		block      string
+		at         string
+		outputFile string
+	)
+	snapshotCommand := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Save the wallet state at a block",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return snapshot(ctx, block, at, outputFile)
+		},
+	}
+	snapshotCommand.Flags().StringP("contract.address", "c", "", "Contract address")
+	snapshotCommand.Flags().StringVarP(&outputFile, "output-file", "o", "", "JSON file where the snapshot is written, it is printed if empty")
+	api.AddBlockFlags(snapshotCommand, &block, &at)
+	snapshotCommand.AddCommand(newSnapshotDiffCommand())
+	return snapshotCommand
+}
+
+// newSnapshotDiffCommand creates the snapshot diff command
+func newSnapshotDiffCommand() *cobra.Command {
+	diffCommand := &cobra.Command{
+		Use:   "diff <before.json> <after.json>",
+		Short: "Print the changes between two snapshots",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return diffSnapshots(args[0], args[1])
+		},
+	}
+	return diffCommand
+}
+
+func snapshot(ctx context.Context, block string, at string, outputFile string) error {
+	client, err := dialNode(ctx)
+	if err != nil {
+		return err
+	}
+	blockRef, err := blockchain.ResolveBlock(ctx, client, block, at)
+	if err != nil {
+		return err
+	}
+	s, err := blockchain.TakeSnapshot(ctx, client, config.App.Contract.Address, blockRef)
+	if err != nil {
+		return err
+	}
+	if outputFile == "" {
+		return output.Print(config.App.Output, walletSnapshot{s})
+	}
+	// the snapshot files are always JSON so they can be compared
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+func diffSnapshots(beforeFile string, afterFile string) error {
+	before, err := readSnapshot(beforeFile)
+	if err != nil {
+		return err
+	}
+	after, err := readSnapshot(afterFile)
+	if err != nil {
+		return err
+	}
+	return output.Print(config.App.Output, snapshotDiff{before: before, after: after, changes: blockchain.DiffSnapshots(before, after)})
+}
+
+// readSnapshot reads a snapshot file
+func readSnapshot(filename string) (blockchain.Snapshot, error) {
+	var s blockchain.Snapshot
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return s, err
+	}
+	if err = json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", filename, err)
+	}
+	return s, nil
+}
+
+// walletSnapshot the snapshot printed by the output formats, the rows are the contract, its owner and the beneficiaries
+type walletSnapshot struct {
+	blockchain.Snapshot
+}
+
+// Columns returns the snapshot columns
+func (s walletSnapshot) Columns() []string {
+	return []string{"subject", "address", "allowance", "balance"}
+}
+
+// Rows returns the contract, owner and beneficiary rows
+func (s walletSnapshot) Rows() [][]string {
+	rows := [][]string{
+		{"contract", s.Contract, "", blockchain.FormatEther(s.Balance)},
+		{"owner", s.Owner, "", ""},
+	}
+	for _, b := range s.Beneficiaries {
+		rows = append(rows, []string{"beneficiary", b.Address, blockchain.FormatEther(b.Allowance), blockchain.FormatEther(b.Balance)})
+	}
+	return rows
+}
+
+// Text prints the block of the snapshot followed by its rows
+func (s walletSnapshot) Text(w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "snapshot of %s at block %d (%s) mined at %s\n\n", s.Contract, s.Block, s.BlockHash, s.Time.Format(time.RFC3339))
+	return output.WriteTable(w, s.Columns(), s.Rows())
+}
+
+// snapshotDiff the changes between two snapshots printed by the output formats
+type snapshotDiff struct {
+	before  blockchain.Snapshot
+	after   blockchain.Snapshot
+	changes []blockchain.SnapshotChange
+}
+
+// Columns returns the change columns
+func (d snapshotDiff) Columns() []string {
+	return []string{"subject", "field", "before", "after", "difference"}
+}
+
+// Rows returns the change rows, the owner and contract changes have the addresses as before and after values
+func (d snapshotDiff) Rows() [][]string {
+	rows := make([][]string, 0, len(d.changes))
+	for _, change := range d.changes {
+		row := []string{change.Subject, change.Field, change.PreviousAddress, change.NewAddress, ""}
+		if change.Difference != nil {
+			row = []string{change.Subject, change.Field, blockchain.FormatEther(change.Before),
+				blockchain.FormatEther(change.After), blockchain.FormatEther(change.Difference)}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Text prints the blocks of the snapshots followed by the changes
+func (d snapshotDiff) Text(w io.Writer) error {
+	_, _ = fmt.Fprintf(w, "from block %d (%s) to block %d (%s)\n\n", d.before.Block, d.before.Time.Format(time.RFC3339),
+		d.after.Block, d.after.Time.Format(time.RFC3339))
+	if len(d.changes) == 0 {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+	return output.WriteTable(w, d.Columns(), d.Rows())
+}
+
+// MarshalJSON returns the changes, no changes is an empty array
+func (d snapshotDiff) MarshalJSON() ([]byte, error) {
+	if d.changes == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(d.changes)
+}
@@ -21,6 +21,7 @@ func main() {
 		log.Println("closing application")
 		signal.Stop(signals)
 		cancel()
+		command.Shutdown()
 	}()
 
 	go func() {
@@ -29,11 +30,13 @@ func main() {
 		cancel()
 		time.Sleep(closeFallbackTime * time.Second)
 		log.Println("fallback exit")
+		command.Shutdown()
 		os.Exit(1)
 	}()
 
 	cmd := command.NewRootCommand(ctx)
 	if err := cmd.Execute(); err != nil {
+		command.Shutdown()
 		os.Exit(1)
 	}
 }
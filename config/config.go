@@ -29,6 +29,14 @@ var (
 type AppConfig struct {
 	Blockchain BlockchainConfig
 	Contract ContractConfig
+	Monitor MonitorConfig
+	Store StoreConfig
+	Budget BudgetConfig
+	Prices PricesConfig
+	// Labels names of the beneficiaries and funders by address
+	Labels map[string]string `mapstructure:"labels"`
+	// Output format of the command results: text, json, yaml, table or csv
+	Output string `mapstructure:"output"`
 }
 
 // BlockchainConfig struct
@@ -48,6 +56,103 @@ type ContractConfig struct {
 	WeiFounds int64 `mapstructure:"default_wei_founds"`
 }
 
+// MonitorConfig struct
+type MonitorConfig struct {
+	Checkpoint string `mapstructure:"checkpoint"`
+	Contracts []MonitoredContract `mapstructure:"contracts"`
+	Rules string `mapstructure:"rules"`
+	Sinks []SinkConfig `mapstructure:"sinks"`
+}
+
+// MonitoredContract struct, a contract watched by the monitor with an optional label
+type MonitoredContract struct {
+	Address string `mapstructure:"address"`
+	Label string `mapstructure:"label"`
+}
+
+// StoreConfig struct, the monitor saves the events in the store file when the path is set
+type StoreConfig struct {
+	Path string `mapstructure:"path"`
+}
+
+// BudgetConfig struct, the limit is the maximum of the outstanding allowances as a ratio of the contract balance,
+// i.e. 1.5, the allowance writes are not checked when it is 0. They are refused past the limit instead of warned when
+// refuse is set
+type BudgetConfig struct {
+	Limit float64 `mapstructure:"limit"`
+	Refuse bool `mapstructure:"refuse"`
+}
+
+// PricesConfig struct, the amounts are valued in the currency with the prices of the CSV file when it is set
+type PricesConfig struct {
+	File string `mapstructure:"file"`
+	Currency string `mapstructure:"currency"`
+}
+
+// SinkConfig struct, the fields used depend on the sink type: stdout, file, webhook or exec
+type SinkConfig struct {
+	Type string `mapstructure:"type"`
+	// Format of the stdout sink: json (default), text, yaml, table or csv
+	Format string `mapstructure:"format"`
+	Path string `mapstructure:"path"`
+	MaxSizeMB int64 `mapstructure:"max_size_mb"`
+	MaxFiles int `mapstructure:"max_files"`
+	URL string `mapstructure:"url"`
+	Secret string `mapstructure:"secret"`
+	Command string `mapstructure:"command"`
+	Args []string `mapstructure:"args"`
+	Timeout time.Duration `mapstructure:"timeout"`
+	Retries int `mapstructure:"retries"`
+	Backoff time.Duration `mapstructure:"backoff"`
+	QueueSize int `mapstructure:"queue_size"`
+	// Durable the checkpoint waits for the sink to deliver the events, the writes wait up to QueueTimeout for room in its queue
+	Durable bool `mapstructure:"durable"`
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+}
+
+// AlertsConfig struct, rules file evaluated by the monitor
+type AlertsConfig struct {
+	Interval time.Duration `mapstructure:"interval"`
+	Notifiers map[string]NotifierConfig `mapstructure:"notifiers"`
+	Rules []RuleConfig `mapstructure:"rules"`
+}
+
+// NotifierConfig struct, the fields used depend on the notifier type: log, webhook or smtp
+type NotifierConfig struct {
+	Type string `mapstructure:"type"`
+	URL string `mapstructure:"url"`
+	Secret string `mapstructure:"secret"`
+	Timeout time.Duration `mapstructure:"timeout"`
+	Host string `mapstructure:"host"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From string `mapstructure:"from"`
+	To []string `mapstructure:"to"`
+}
+
+// RuleConfig struct, the threshold is an ether amount
+type RuleConfig struct {
+	Name string `mapstructure:"name"`
+	Condition string `mapstructure:"condition"`
+	Threshold string `mapstructure:"threshold"`
+	Beneficiary string `mapstructure:"beneficiary"`
+	Notify []string `mapstructure:"notify"`
+	Dedup time.Duration `mapstructure:"dedup"`
+	Cooldown time.Duration `mapstructure:"cooldown"`
+}
+
+// LoadAlerts reads the alert rules file
+func LoadAlerts(filename string) (AlertsConfig, error) {
+	var alerts AlertsConfig
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return alerts, err
+	}
+	err := v.Unmarshal(&alerts)
+	return alerts, err
+}
+
 // Setup bind command flags and environment variables
 // The precedence to override a configuration is: flag -> environment variable -> configuration field
 func Setup(cmd *cobra.Command, _ []string) error {
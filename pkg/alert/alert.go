@@ -0,0 +1,381 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BalanceBelow            = "balance_below"
+	AllowanceZero           = "allowance_zero"
+	OwnershipTransferred    = "ownership_transferred"
+	MoneySentAbove          = "money_sent_above"
+	AllowancesExceedBalance = "allowances_exceed_balance"
+
+	defaultInterval = time.Minute
+	defaultDedup    = time.Hour
+	// pruneSize number of notifications kept before removing the expired ones
+	pruneSize = 1024
+)
+
+var (
+	ErrInvalidCondition = errors.New("invalid rule condition")
+	ErrMissingThreshold = errors.New("rule threshold is required")
+	ErrUnknownNotifier  = errors.New("unknown notifier")
+)
+
+// Alert struct, sent to the notifiers of the rule
+type Alert struct {
+	Rule      string           `json:"rule"`
+	Condition string           `json:"condition"`
+	Subject   string           `json:"subject"`
+	Message   string           `json:"message"`
+	Contract  string           `json:"contract"`
+	Label     string           `json:"label,omitempty"`
+	Time      time.Time        `json:"time"`
+	Event     blockchain.Event `json:"event,omitempty"`
+}
+
+// rule evaluated condition with its notifiers
+type rule struct {
+	config.RuleConfig
+	threshold *big.Int
+	notifiers []Notifier
+}
+
+// Engine interface, it receives the monitor events as a sink and checks the contract state on every interval
+type Engine interface {
+	Write(ctx context.Context, event blockchain.Event) error
+	Run(ctx context.Context) error
+	Close() error
+}
+
+type engine struct {
+	contract string
+	label    string
+	state    blockchain.ContractState
+	// head block at startup, the events up to it are replayed history and do not fire the event rules
+	head          uint64
+	interval      time.Duration
+	rules         []*rule
+	wake          chan struct{}
+	mutex         sync.Mutex
+	beneficiaries map[common.Address]struct{}
+	// zero last known allowance state of the beneficiaries, the allowance_zero rules fire when it changes to zero
+	zero map[common.Address]bool
+	// notified last notification time by rule and subject, used for the dedup window
+	notified map[string]time.Time
+	// lastNotified last notification time by rule, used for the cooldown window
+	lastNotified map[string]time.Time
+}
+
+// NewEngine creates the rules and their notifiers of a contract, the rules without notifiers are written in the log.
+// The events up to the head block do not fire the event rules
+func NewEngine(cfg config.AlertsConfig, contract string, label string, state blockchain.ContractState, head uint64) (Engine, error) {
+	notifiers := map[string]Notifier{LogNotifier: &logNotifier{}}
+	for name, notifierConfig := range cfg.Notifiers {
+		notifier, err := NewNotifier(notifierConfig)
+		if err != nil {
+			return nil, fmt.Errorf("%s notifier: %w", name, err)
+		}
+		notifiers[strings.ToLower(name)] = notifier
+	}
+	e := &engine{
+		contract:      contract,
+		label:         label,
+		state:         state,
+		head:          head,
+		interval:      cfg.Interval,
+		wake:          make(chan struct{}, 1),
+		beneficiaries: make(map[common.Address]struct{}),
+		zero:          make(map[common.Address]bool),
+		notified:      make(map[string]time.Time),
+		lastNotified:  make(map[string]time.Time),
+	}
+	if e.interval == 0 {
+		e.interval = defaultInterval
+	}
+	for i, ruleConfig := range cfg.Rules {
+		if ruleConfig.Name == "" {
+			ruleConfig.Name = fmt.Sprintf("%s-%d", ruleConfig.Condition, i+1)
+		}
+		r, err := newRule(ruleConfig, notifiers)
+		if err != nil {
+			return nil, fmt.Errorf("%s rule: %w", ruleConfig.Name, err)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+func newRule(cfg config.RuleConfig, notifiers map[string]Notifier) (*rule, error) {
+	r := &rule{RuleConfig: cfg}
+	switch cfg.Condition {
+	case BalanceBelow, MoneySentAbove:
+		if cfg.Threshold == "" {
+			return nil, ErrMissingThreshold
+		}
+		threshold, err := blockchain.ParseEther(cfg.Threshold)
+		if err != nil {
+			return nil, err
+		}
+		r.threshold = threshold
+	case AllowanceZero, OwnershipTransferred, AllowancesExceedBalance:
+	default:
+		return nil, ErrInvalidCondition
+	}
+	if r.Dedup == 0 {
+		r.Dedup = defaultDedup
+	}
+	names := cfg.Notify
+	if len(names) == 0 {
+		names = []string{LogNotifier}
+	}
+	for _, name := range names {
+		notifier, ok := notifiers[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNotifier, name)
+		}
+		r.notifiers = append(r.notifiers, notifier)
+	}
+	return r, nil
+}
+
+// Write checks the event rules and wakes the state rules up, the beneficiaries of the events are kept to check their allowances.
+// The events of other contracts are ignored and the replayed ones only add their beneficiaries
+func (e *engine) Write(ctx context.Context, event blockchain.Event) error {
+	if event.Type() == blockchain.Retracted || common.HexToAddress(event.Log().Contract) != common.HexToAddress(e.contract) {
+		return nil
+	}
+	if event.Log().BlockNumber > e.head {
+		e.checkEvent(ctx, event)
+	}
+
+	var beneficiary string
+	switch changed := event.(type) {
+	case *blockchain.AllowanceChangedEvent:
+		beneficiary = changed.Beneficiary
+	case *blockchain.MoneySentEvent:
+		beneficiary = changed.Beneficiary
+	}
+	e.mutex.Lock()
+	if beneficiary != "" {
+		address := common.HexToAddress(beneficiary)
+		e.beneficiaries[address] = struct{}{}
+		// a beneficiary first seen in a new event had an allowance before it
+		if _, ok := e.zero[address]; !ok && event.Log().BlockNumber > e.head {
+			e.zero[address] = false
+		}
+	}
+	e.mutex.Unlock()
+
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// checkEvent checks the event rules
+func (e *engine) checkEvent(ctx context.Context, event blockchain.Event) {
+	subject := fmt.Sprintf("%s:%d", event.Log().TxHash, event.Log().LogIndex)
+	for _, r := range e.rules {
+		switch r.Condition {
+		case OwnershipTransferred:
+			if transferred, ok := event.(*blockchain.OwnershipTransferredEvent); ok {
+				e.notify(ctx, r, subject, event, "ownership transferred from %s to %s", transferred.PreviousOwner, transferred.NewOwner)
+			}
+		case MoneySentAbove:
+			if sent, ok := event.(*blockchain.MoneySentEvent); ok && sent.Amount != nil {
+				amount := sent.Amount
+				if amount.Cmp(r.threshold) > 0 {
+					e.notify(ctx, r, subject, event, "%s ether sent to %s, above %s", blockchain.FormatEther(amount), sent.Beneficiary, r.Threshold)
+				}
+			}
+		}
+	}
+}
+
+// Run checks the state rules on every interval and after the events until the context is done
+func (e *engine) Run(ctx context.Context) error {
+	if !e.hasStateRules() {
+		return nil
+	}
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-e.wake:
+		}
+		if err := e.check(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("unable to check the %s alert rules: %s\n", e.contract, err.Error())
+		}
+	}
+}
+
+// Close does nothing, the notifications are sent synchronously
+func (e *engine) Close() error {
+	return nil
+}
+
+// hasStateRules reports whether there are rules checking the contract state
+func (e *engine) hasStateRules() bool {
+	for _, r := range e.rules {
+		if r.Condition == BalanceBelow || r.Condition == AllowanceZero || r.Condition == AllowancesExceedBalance {
+			return true
+		}
+	}
+	return false
+}
+
+// check reads the contract balance and allowances and checks the state rules. A condition no longer true
+// is resolved, so it is notified again as soon as it happens again. An allowance is only notified when it changes to
+// zero, the first read of a beneficiary allowance keeps its state
+func (e *engine) check(ctx context.Context) error {
+	balance, err := e.state.Balance(ctx)
+	if err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	beneficiaries := make([]string, 0, len(e.beneficiaries))
+	for beneficiary := range e.beneficiaries {
+		beneficiaries = append(beneficiaries, beneficiary.Hex())
+	}
+	e.mutex.Unlock()
+	for _, r := range e.rules {
+		if r.Beneficiary != "" {
+			beneficiaries = appendAddress(beneficiaries, r.Beneficiary)
+		}
+	}
+	allowances := make(map[string]*big.Int, len(beneficiaries))
+	total := new(big.Int)
+	for _, beneficiary := range beneficiaries {
+		allowance, err := e.state.Allowance(ctx, beneficiary)
+		if err != nil {
+			return err
+		}
+		allowances[beneficiary] = allowance
+		total.Add(total, allowance)
+	}
+	becameZero := e.updateZero(allowances)
+
+	for _, r := range e.rules {
+		switch r.Condition {
+		case BalanceBelow:
+			e.evaluate(ctx, r, e.contract, balance.Cmp(r.threshold) < 0,
+				"contract balance %s ether is below %s", blockchain.FormatEther(balance), r.Threshold)
+		case AllowancesExceedBalance:
+			e.evaluate(ctx, r, e.contract, total.Cmp(balance) > 0,
+				"total allowances %s ether exceed the contract balance %s ether", blockchain.FormatEther(total), blockchain.FormatEther(balance))
+		case AllowanceZero:
+			for beneficiary := range allowances {
+				if r.Beneficiary != "" && common.HexToAddress(r.Beneficiary) != common.HexToAddress(beneficiary) {
+					continue
+				}
+				e.evaluate(ctx, r, beneficiary, becameZero[beneficiary], "allowance of %s reached zero", beneficiary)
+			}
+		}
+	}
+	return nil
+}
+
+// updateZero keeps the allowance state of the beneficiaries and returns the ones whose allowance changed to zero
+func (e *engine) updateZero(allowances map[string]*big.Int) map[string]bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	becameZero := make(map[string]bool)
+	for beneficiary, allowance := range allowances {
+		address := common.HexToAddress(beneficiary)
+		zero, known := e.zero[address]
+		e.zero[address] = allowance.Sign() == 0
+		becameZero[beneficiary] = known && !zero && allowance.Sign() == 0
+	}
+	return becameZero
+}
+
+// evaluate notifies an active condition or resolves it
+func (e *engine) evaluate(ctx context.Context, r *rule, subject string, active bool, format string, args ...interface{}) {
+	if active {
+		e.notify(ctx, r, subject, nil, format, args...)
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(e.notified, r.Name+"|"+subject)
+}
+
+// notify sends the alert to the rule notifiers unless the same alert was sent within the dedup window
+// or the rule sent any alert within the cooldown window
+func (e *engine) notify(ctx context.Context, r *rule, subject string, event blockchain.Event, format string, args ...interface{}) {
+	now := time.Now()
+	key := r.Name + "|" + subject
+	e.mutex.Lock()
+	if last, ok := e.notified[key]; ok && now.Sub(last) < r.Dedup {
+		e.mutex.Unlock()
+		return
+	}
+	if last, ok := e.lastNotified[r.Name]; ok && now.Sub(last) < r.Cooldown {
+		e.mutex.Unlock()
+		return
+	}
+	e.prune(now)
+	e.notified[key] = now
+	e.lastNotified[r.Name] = now
+	e.mutex.Unlock()
+
+	alert := Alert{
+		Rule:      r.Name,
+		Condition: r.Condition,
+		Subject:   subject,
+		Message:   fmt.Sprintf(format, args...),
+		Contract:  e.contract,
+		Label:     e.label,
+		Time:      now,
+		Event:     event,
+	}
+	for _, notifier := range r.notifiers {
+		if err := notifier.Notify(ctx, alert); err != nil {
+			log.Printf("unable to notify %s alert: %s\n", r.Name, err.Error())
+		}
+	}
+}
+
+// prune removes the notifications older than every dedup window, the caller should hold the mutex
+func (e *engine) prune(now time.Time) {
+	if len(e.notified) < pruneSize {
+		return
+	}
+	var window time.Duration
+	for _, r := range e.rules {
+		if r.Dedup > window {
+			window = r.Dedup
+		}
+	}
+	for key, last := range e.notified {
+		if now.Sub(last) >= window {
+			delete(e.notified, key)
+		}
+	}
+}
+
+// appendAddress appends the address unless it is already in the list
+func appendAddress(addresses []string, address string) []string {
+	for _, a := range addresses {
+		if common.HexToAddress(a) == common.HexToAddress(address) {
+			return addresses
+		}
+	}
+	return append(addresses, common.HexToAddress(address).Hex())
+}
@@ -0,0 +1,143 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/sink"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const (
+	LogNotifier     = "log"
+	WebhookNotifier = "webhook"
+	SMTPNotifier    = "smtp"
+
+	defaultTimeout = 10 * time.Second
+	// RuleHeader header with the rule name of the alert
+	RuleHeader = "X-Wallet-Rule"
+)
+
+var (
+	ErrInvalidNotifierType = errors.New("invalid notifier type")
+	ErrMissingURL          = errors.New("webhook notifier url is required")
+	ErrMissingRecipients   = errors.New("smtp notifier host, from and to are required")
+)
+
+// Notifier interface implemented by the alert destinations
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// NewNotifier creates a notifier from its configuration
+func NewNotifier(cfg config.NotifierConfig) (Notifier, error) {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	switch cfg.Type {
+	case LogNotifier:
+		return &logNotifier{}, nil
+	case WebhookNotifier:
+		if cfg.URL == "" {
+			return nil, ErrMissingURL
+		}
+		return &webhookNotifier{
+			url:    cfg.URL,
+			secret: []byte(cfg.Secret),
+			client: &http.Client{Timeout: cfg.Timeout},
+		}, nil
+	case SMTPNotifier:
+		if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, ErrMissingRecipients
+		}
+		return &smtpNotifier{
+			host:     cfg.Host,
+			username: cfg.Username,
+			password: cfg.Password,
+			from:     cfg.From,
+			to:       cfg.To,
+		}, nil
+	}
+	return nil, ErrInvalidNotifierType
+}
+
+type logNotifier struct{}
+
+// Notify writes the alert in the application log
+func (n *logNotifier) Notify(_ context.Context, alert Alert) error {
+	contract := alert.Contract
+	if alert.Label != "" {
+		contract = alert.Label
+	}
+	log.Printf("ALERT %s on %s: %s\n", alert.Rule, contract, alert.Message)
+	return nil
+}
+
+type webhookNotifier struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// Notify posts the alert as JSON, signed like the webhook sink events
+func (n *webhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(RuleHeader, alert.Rule)
+	if len(n.secret) > 0 {
+		request.Header.Set(sink.SignatureHeader, "sha256="+sink.Sign(n.secret, body))
+	}
+	response, err := n.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+type smtpNotifier struct {
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// Notify sends the alert by email, the server is authenticated only when there is a username
+func (n *smtpNotifier) Notify(_ context.Context, alert Alert) error {
+	var auth smtp.Auth
+	if n.username != "" {
+		host, _, err := net.SplitHostPort(n.host)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", n.username, n.password, host)
+	}
+	var message strings.Builder
+	message.WriteString("From: " + n.from + "\r\n")
+	message.WriteString("To: " + strings.Join(n.to, ", ") + "\r\n")
+	message.WriteString("Subject: [wallet] " + alert.Rule + "\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(alert.Message + "\r\n\r\n")
+	message.WriteString("Contract: " + alert.Contract + "\r\n")
+	message.WriteString("Time: " + alert.Time.Format(time.RFC3339) + "\r\n")
+	return smtp.SendMail(n.host, auth, n.from, n.to, []byte(message.String()))
+}
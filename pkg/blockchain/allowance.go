@@ -2,11 +2,11 @@ package blockchain
 
 import (
 	"context"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"math/big"
+	"time"
 )
 
 const (
@@ -14,12 +14,22 @@ const (
 	GetAction = "get"
 	IncreaseAction = "increase"
 	ReduceAction = "reduce"
+	ListAction = "list"
 )
 
+// BeneficiaryAllowance struct, the current allowance of a beneficiary in wei with its last change
+type BeneficiaryAllowance struct {
+	Beneficiary string `json:"beneficiary"`
+	Allowance *big.Int `json:"allowance"`
+	LastChange time.Time `json:"last_change"`
+	LastChangeBlock uint64 `json:"last_change_block"`
+}
+
 // Allowance interface
 type Allowance interface {
-	GetAllowance(ctx context.Context, client *ethclient.Client, beneficiaryAddress string) (int64, error)
-	ChangeAllowance(ctx context.Context, client *ethclient.Client, action string, target string, amount int64) error
+	GetAllowance(ctx context.Context, client *ethclient.Client, beneficiaryAddress string, block BlockRef) (*big.Int, error)
+	ChangeAllowance(ctx context.Context, client *ethclient.Client, action string, target string, amount int64) (TransactionInfo, error)
+	ListAllowances(ctx context.Context, client *ethclient.Client, block BlockRef) ([]BeneficiaryAllowance, error)
 }
 
 type allowance struct {
@@ -35,28 +45,28 @@ func NewAllowanceRunner(privateKey string, contractAddress string) Allowance {
 	}
 }
 
-// GetAllowance get allowance value for a given address
-func (r *allowance) GetAllowance(ctx context.Context, client *ethclient.Client, beneficiaryAddress string) (int64, error) {
+// GetAllowance get allowance value in wei for a given address at a block
+func (r *allowance) GetAllowance(ctx context.Context, client *ethclient.Client, beneficiaryAddress string, block BlockRef) (*big.Int, error) {
 	contract, err := getContract(ctx, client, r.contractAddress)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	address := common.HexToAddress(beneficiaryAddress)
-	amount, err := contract.Allowance(&bind.CallOpts{Pending: false, Context: ctx}, address)
-	return weiToEther(amount).Int64(), nil
+	amount, err := contract.Allowance(block.callOpts(ctx), address)
+	return amount, stateError(err)
 }
 
-// ChangeAllowance change the allowance value for a given address
-func (r *allowance) ChangeAllowance(ctx context.Context, client *ethclient.Client, action string, target string, amount int64) error {
+// ChangeAllowance change the allowance value for a given address, it returns the mined transaction
+func (r *allowance) ChangeAllowance(ctx context.Context, client *ethclient.Client, action string, target string, amount int64) (TransactionInfo, error) {
 	contract, err := getContract(ctx, client, r.contractAddress)
 	if err != nil {
-		return err
+		return TransactionInfo{}, err
 	}
 
 	signer, err := getSigner(ctx, client)
 	if err != nil {
-		return err
+		return TransactionInfo{}, err
 	}
 
 	var tx *types.Transaction
@@ -75,14 +85,61 @@ func (r *allowance) ChangeAllowance(ctx context.Context, client *ethclient.Clien
 			tx, txErr = contract.ReduceAllowance(signer, targetAddress, etherToWei(big.NewInt(amount)))
 			operation = "reduce_allowance"
 	}
-	if txErr != nil {
-		return txErr
-	}
-	receipt, err := bind.WaitMined(ctx, client, tx)
-	if receipt.Status != types.ReceiptStatusSuccessful || err != nil {
-		return err
+	return waitTransaction(ctx, client, tx, txErr, operation)
+}
+
+// ListAllowances returns the beneficiaries with a non-zero allowance at a block
+func (r *allowance) ListAllowances(ctx context.Context, client *ethclient.Client, block BlockRef) ([]BeneficiaryAllowance, error) {
+	number, err := block.Resolve(ctx, client)
+	if err != nil {
+		return nil, err
 	}
-	processTransaction(ctx, tx, operation)
+	return currentAllowances(ctx, client, r.contractAddress, number)
+}
 
-	return nil
-}
\ No newline at end of file
+// currentAllowances returns the beneficiaries with a non-zero allowance at a block, the latest one if nil. The contract
+// mapping can't be enumerated so the beneficiaries are the addresses seen in the AllowanceChanged events
+func currentAllowances(ctx context.Context, client *ethclient.Client, contractAddress string, block *big.Int) ([]BeneficiaryAllowance, error) {
+	history, err := NewHistory(client, contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	query := HistoryQuery{Types: []string{AllowanceChanged}}
+	if block != nil {
+		toBlock := block.Uint64()
+		query.ToBlock = &toBlock
+	}
+	records, err := history.Records(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	lastChanges := map[string]Record{}
+	var beneficiaries []string
+	for _, record := range records {
+		if _, ok := lastChanges[record.Beneficiary]; !ok {
+			beneficiaries = append(beneficiaries, record.Beneficiary)
+		}
+		lastChanges[record.Beneficiary] = record
+	}
+	state, err := NewContractState(client, contractAddress)
+	if err != nil {
+		return nil, err
+	}
+	allowances, err := state.At(block).Allowances(ctx, beneficiaries)
+	if err != nil {
+		return nil, err
+	}
+	var list []BeneficiaryAllowance
+	for _, beneficiary := range beneficiaries {
+		if allowances[beneficiary].Sign() == 0 {
+			continue
+		}
+		list = append(list, BeneficiaryAllowance{
+			Beneficiary: beneficiary,
+			Allowance: allowances[beneficiary],
+			LastChange: lastChanges[beneficiary].Timestamp,
+			LastChangeBlock: lastChanges[beneficiary].BlockNumber,
+		})
+	}
+	return list, nil
+}
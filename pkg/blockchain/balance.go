@@ -2,19 +2,20 @@ package blockchain
 
 import (
 	"context"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
 )
 
 const (
 	AddressBalance = "address"
 	ContractBalance = "contract"
+	OwnerBalance = "owner"
 )
 
 // Balance interface
 type Balance interface {
-	GetContractBalance(ctx context.Context, client *ethclient.Client) (int64, error)
-	GetAddressBalance(ctx context.Context, client *ethclient.Client, address string) (int64, error)
+	GetContractBalance(ctx context.Context, client *ethclient.Client, block BlockRef) (*big.Int, error)
+	GetAddressBalance(ctx context.Context, client *ethclient.Client, address string, block BlockRef) (*big.Int, error)
 }
 
 type balance struct {
@@ -30,20 +31,12 @@ func NewBalanceRunner(privateKey string, contractAddress string) Balance {
 	}
 }
 
-// GetContractBalance returns the contract balance
-func (b *balance) GetContractBalance(ctx context.Context, client *ethclient.Client) (int64, error) {
-	value, err := client.BalanceAt(ctx, common.HexToAddress(b.contractAddress), nil)
-	if err != nil {
-		return 0, err
-	}
-	return weiToEther(value).Int64(), nil
+// GetContractBalance returns the contract balance in wei at a block
+func (b *balance) GetContractBalance(ctx context.Context, client *ethclient.Client, block BlockRef) (*big.Int, error) {
+	return block.balanceAt(ctx, client, b.contractAddress)
 }
 
-// GetAddressBalance returns the balance of a given address
-func (b *balance) GetAddressBalance(ctx context.Context, client *ethclient.Client, address string) (int64, error) {
-	value, err := client.BalanceAt(ctx, common.HexToAddress(address), nil)
-	if err != nil {
-		return 0, err
-	}
-	return weiToEther(value).Int64(), nil
+// GetAddressBalance returns the balance in wei of a given address at a block
+func (b *balance) GetAddressBalance(ctx context.Context, client *ethclient.Client, address string, block BlockRef) (*big.Int, error) {
+	return block.balanceAt(ctx, client, address)
 }
\ No newline at end of file
@@ -0,0 +1,163 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LatestBlock = "latest"
+	PendingBlock = "pending"
+)
+
+var (
+	ErrInvalidBlock = errors.New("block should be a block number, latest or pending")
+	ErrInvalidTime = errors.New("time should be YYYY-MM-DD, RFC3339 or unix seconds")
+	ErrBlockAndTime = errors.New("block and time can't be used together")
+	ErrNoBlockAt = errors.New("there is no block mined at or before the time")
+	ErrPendingHistory = errors.New("the pending block has no event history, use latest or a block number")
+	ErrPrunedState = errors.New("the node pruned the state of the block, reading older blocks needs an archive node")
+)
+
+// BlockRef struct, the block the state is read at: a block number, the pending block or the latest one when the number
+// is nil
+type BlockRef struct {
+	Number *big.Int
+	Pending bool
+}
+
+// String returns the block number, latest or pending
+func (b BlockRef) String() string {
+	switch {
+	case b.Pending:
+		return PendingBlock
+	case b.Number == nil:
+		return LatestBlock
+	}
+	return b.Number.String()
+}
+
+// callOpts returns the options of the contract calls at the block
+func (b BlockRef) callOpts(ctx context.Context) *bind.CallOpts {
+	return &bind.CallOpts{Pending: b.Pending, BlockNumber: b.Number, Context: ctx}
+}
+
+// balanceAt returns the balance of an address at the block
+func (b BlockRef) balanceAt(ctx context.Context, client *ethclient.Client, address string) (*big.Int, error) {
+	if b.Pending {
+		return client.PendingBalanceAt(ctx, common.HexToAddress(address))
+	}
+	balance, err := client.BalanceAt(ctx, common.HexToAddress(address), b.Number)
+	return balance, stateError(err)
+}
+
+// Resolve returns the number of the block, the latest block number is read when it is not set. The pending block is
+// refused since the event history can't be read at it
+func (b BlockRef) Resolve(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+	if b.Pending {
+		return nil, ErrPendingHistory
+	}
+	if b.Number != nil {
+		return b.Number, nil
+	}
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return header.Number, nil
+}
+
+// ResolveBlock returns the block of a block number, latest or pending, or the last block mined at or before a time. A
+// date without time is the end of the day in UTC, so the block holds the state at the close of that day
+func ResolveBlock(ctx context.Context, client *ethclient.Client, block string, at string) (BlockRef, error) {
+	if at != "" {
+		if block != "" && block != LatestBlock {
+			return BlockRef{}, ErrBlockAndTime
+		}
+		t, err := parseTime(at)
+		if err != nil {
+			return BlockRef{}, err
+		}
+		number, err := BlockAt(ctx, client, t)
+		if err != nil {
+			return BlockRef{}, err
+		}
+		return BlockRef{Number: number}, nil
+	}
+	switch block {
+	case "", LatestBlock:
+		return BlockRef{}, nil
+	case PendingBlock:
+		return BlockRef{Pending: true}, nil
+	}
+	number, ok := new(big.Int).SetString(block, 10)
+	if !ok || number.Sign() < 0 {
+		return BlockRef{}, ErrInvalidBlock
+	}
+	return BlockRef{Number: number}, nil
+}
+
+// BlockAt returns the number of the last block mined at or before a time, it binary searches the block timestamps
+func BlockAt(ctx context.Context, client *ethclient.Client, t time.Time) (*big.Int, error) {
+	target := uint64(t.Unix())
+	latest, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Time <= target {
+		return latest.Number, nil
+	}
+	// the invariant is that the low block is mined at or before the time and the high block after it
+	low, high := uint64(0), latest.Number.Uint64()
+	genesis, err := client.HeaderByNumber(ctx, new(big.Int))
+	if err != nil {
+		return nil, err
+	}
+	if genesis.Time > target {
+		return nil, fmt.Errorf("%w: %s", ErrNoBlockAt, t.UTC().Format(time.RFC3339))
+	}
+	for high-low > 1 {
+		middle := low + (high-low)/2
+		header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(middle))
+		if err != nil {
+			return nil, err
+		}
+		if header.Time <= target {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return new(big.Int).SetUint64(low), nil
+}
+
+// stateError replaces the missing trie node errors of the nodes without the state of old blocks
+func stateError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "missing trie node") {
+		return ErrPrunedState
+	}
+	return err
+}
+
+// parseTime parses a YYYY-MM-DD date as the end of the day, a RFC3339 time or unix seconds
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Time{}, ErrInvalidTime
+}
@@ -0,0 +1,158 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// fakeChain serves the eth_getBlockByNumber calls of the block search from the block timestamps
+type fakeChain struct {
+	times []uint64
+}
+
+// GetBlockByNumber returns the header of a block, the latest one for the latest tag
+func (c *fakeChain) GetBlockByNumber(_ context.Context, number rpc.BlockNumber, _ bool) (*types.Header, error) {
+	n := int64(number)
+	if number == rpc.LatestBlockNumber {
+		n = int64(len(c.times) - 1)
+	}
+	if n < 0 || n >= int64(len(c.times)) {
+		return nil, fmt.Errorf("unknown block %d", n)
+	}
+	return &types.Header{Number: big.NewInt(n), Time: c.times[n], Difficulty: new(big.Int)}, nil
+}
+
+// newFakeClient returns a client of an in-process chain mined at the given unix times
+func newFakeClient(t *testing.T, times ...uint64) *ethclient.Client {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &fakeChain{times: times}); err != nil {
+		t.Fatal(err)
+	}
+	client := ethclient.NewClient(rpc.DialInProc(server))
+	t.Cleanup(func() {
+		client.Close()
+		server.Stop()
+	})
+	return client
+}
+
+func TestBlockAt(t *testing.T) {
+	// the blocks 3 and 4 are mined in the same second
+	client := newFakeClient(t, 1000, 1010, 1020, 1030, 1030, 1045, 1060)
+	tests := []struct {
+		name string
+		at   int64
+		want int64
+		err  error
+	}{
+		{"before genesis", 999, 0, ErrNoBlockAt},
+		{"genesis", 1000, 0, nil},
+		{"between two blocks", 1015, 1, nil},
+		{"exact timestamp", 1020, 2, nil},
+		{"last block of a second", 1030, 4, nil},
+		{"before the head", 1059, 5, nil},
+		{"head", 1060, 6, nil},
+		{"after head", 5000, 6, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := BlockAt(context.Background(), client, time.Unix(test.at, 0))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("BlockAt(%d) error = %v, want %v", test.at, err, test.err)
+			}
+			if err == nil && got.Int64() != test.want {
+				t.Errorf("BlockAt(%d) = %s, want %d", test.at, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBlockAtSingleBlock(t *testing.T) {
+	client := newFakeClient(t, 1000)
+	if got, err := BlockAt(context.Background(), client, time.Unix(1000, 0)); err != nil || got.Int64() != 0 {
+		t.Errorf("BlockAt = %v, %v, want the genesis", got, err)
+	}
+	if _, err := BlockAt(context.Background(), client, time.Unix(999, 0)); !errors.Is(err, ErrNoBlockAt) {
+		t.Errorf("BlockAt before genesis = %v, want %v", err, ErrNoBlockAt)
+	}
+}
+
+func TestResolveBlock(t *testing.T) {
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	endOfDay := day.AddDate(0, 0, 1).Add(-time.Second)
+	client := newFakeClient(t,
+		uint64(day.Add(-time.Hour).Unix()),
+		uint64(day.Add(12*time.Hour).Unix()),
+		uint64(endOfDay.Unix()),
+		uint64(endOfDay.Add(time.Second).Unix()),
+		uint64(endOfDay.Add(time.Hour).Unix()),
+	)
+	tests := []struct {
+		name  string
+		block string
+		at    string
+		want  string
+		err   error
+	}{
+		{"default", "", "", LatestBlock, nil},
+		{"latest", LatestBlock, "", LatestBlock, nil},
+		{"pending", PendingBlock, "", PendingBlock, nil},
+		{"number", "42", "", "42", nil},
+		{"negative number", "-1", "", "", ErrInvalidBlock},
+		{"invalid block", "0x10", "", "", ErrInvalidBlock},
+		{"date is the end of the UTC day", "", "2026-03-01", "2", nil},
+		{"date of the previous day", "", "2026-02-28", "0", nil},
+		{"date before genesis", "", "2026-02-27", "", ErrNoBlockAt},
+		{"date after head", "", "2026-03-05", "4", nil},
+		{"RFC3339 time", "", "2026-03-01T12:00:00Z", "1", nil},
+		{"RFC3339 time with offset", "", "2026-03-01T19:00:00-05:00", "3", nil},
+		{"unix seconds", "", fmt.Sprint(endOfDay.Unix() - 1), "1", nil},
+		{"time with latest", LatestBlock, "2026-03-01", "2", nil},
+		{"time with a block", "3", "2026-03-01", "", ErrBlockAndTime},
+		{"time with pending", PendingBlock, "2026-03-01", "", ErrBlockAndTime},
+		{"invalid time", "", "yesterday", "", ErrInvalidTime},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ResolveBlock(context.Background(), client, test.block, test.at)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("ResolveBlock(%q, %q) error = %v, want %v", test.block, test.at, err, test.err)
+			}
+			if err == nil && got.String() != test.want {
+				t.Errorf("ResolveBlock(%q, %q) = %s, want %s", test.block, test.at, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2026-03-01", time.Date(2026, 3, 1, 23, 59, 59, 0, time.UTC)},
+		{" 2026-12-31 ", time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{"2026-03-01T10:20:30Z", time.Date(2026, 3, 1, 10, 20, 30, 0, time.UTC)},
+		{"2026-03-01T10:20:30+02:00", time.Date(2026, 3, 1, 8, 20, 30, 0, time.UTC)},
+		{"1772323200", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got, err := parseTime(test.value)
+		if err != nil || !got.Equal(test.want) {
+			t.Errorf("parseTime(%q) = %s, %v, want %s", test.value, got, err, test.want)
+		}
+	}
+	for _, value := range []string{"", "2026-13-01", "01/03/2026", "tomorrow"} {
+		if _, err := parseTime(value); !errors.Is(err, ErrInvalidTime) {
+			t.Errorf("parseTime(%q) = %v, want %v", value, err, ErrInvalidTime)
+		}
+	}
+}
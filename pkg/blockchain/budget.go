@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+	"strings"
+)
+
+var ErrOverCommitted = errors.New("the allowances would exceed the over-commit limit of the contract balance")
+
+// Budget struct, the outstanding allowances compared with the contract balance at the same block, the amounts are in
+// wei. The coverage is the balance over the allowances, it is nil when there are no allowances
+type Budget struct {
+	Block uint64 `json:"block"`
+	Balance *big.Int `json:"balance"`
+	Allowances *big.Int `json:"allowances"`
+	OverCommitment *big.Int `json:"over_commitment"`
+	Coverage *float64 `json:"coverage"`
+	Beneficiaries []BeneficiaryAllowance `json:"beneficiaries"`
+}
+
+// ReadBudget sums the allowances of the beneficiaries known from the events and reads the contract balance at a block
+func ReadBudget(ctx context.Context, client *ethclient.Client, contractAddress string, block BlockRef) (Budget, error) {
+	number, err := block.Resolve(ctx, client)
+	if err != nil {
+		return Budget{}, err
+	}
+	beneficiaries, err := currentAllowances(ctx, client, contractAddress, number)
+	if err != nil {
+		return Budget{}, err
+	}
+	balance, err := client.BalanceAt(ctx, common.HexToAddress(contractAddress), number)
+	if err != nil {
+		return Budget{}, err
+	}
+	allowances := new(big.Int)
+	for _, beneficiary := range beneficiaries {
+		allowances.Add(allowances, beneficiary.Allowance)
+	}
+	budget := Budget{
+		Block: number.Uint64(),
+		Balance: balance,
+		Allowances: allowances,
+		OverCommitment: new(big.Int),
+		Beneficiaries: beneficiaries,
+	}
+	if allowances.Cmp(balance) > 0 {
+		budget.OverCommitment.Sub(allowances, balance)
+	}
+	if allowances.Sign() > 0 {
+		coverage, _ := new(big.Rat).SetFrac(balance, allowances).Float64()
+		budget.Coverage = &coverage
+	}
+	return budget, nil
+}
+
+// After returns the outstanding allowances after an allowance change of a beneficiary, the amount is in wei
+func (b Budget) After(action string, beneficiary string, amount *big.Int) *big.Int {
+	allowances := new(big.Int).Set(b.Allowances)
+	current := new(big.Int)
+	for _, a := range b.Beneficiaries {
+		if strings.EqualFold(a.Beneficiary, beneficiary) {
+			current = a.Allowance
+		}
+	}
+	switch action {
+	case SetAction:
+		allowances.Sub(allowances, current).Add(allowances, amount)
+	case IncreaseAction:
+		allowances.Add(allowances, amount)
+	case ReduceAction:
+		if amount.Cmp(current) > 0 {
+			amount = current
+		}
+		allowances.Sub(allowances, amount)
+	}
+	return allowances
+}
+
+// Exceeds reports whether the allowances exceed the limit, a ratio of the contract balance. A zero limit is never
+// exceeded
+func (b Budget) Exceeds(allowances *big.Int, limit float64) bool {
+	if limit <= 0 {
+		return false
+	}
+	maximum := new(big.Rat).Mul(new(big.Rat).SetFloat64(limit), new(big.Rat).SetInt(b.Balance))
+	return new(big.Rat).SetInt(allowances).Cmp(maximum) > 0
+}
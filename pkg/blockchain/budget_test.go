@@ -0,0 +1,67 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBudgetAfter(t *testing.T) {
+	budget := Budget{
+		Balance:    wei(t, "10"),
+		Allowances: wei(t, "6"),
+		Beneficiaries: []BeneficiaryAllowance{
+			{Beneficiary: alice, Allowance: wei(t, "4")},
+			{Beneficiary: bob, Allowance: wei(t, "2")},
+		},
+	}
+	tests := []struct {
+		name        string
+		action      string
+		beneficiary string
+		amount      string
+		want        string
+	}{
+		{"set replaces the allowance", SetAction, alice, "1", "3"},
+		{"set of a new beneficiary", SetAction, ownerAddress, "5", "11"},
+		{"set ignores the address case", SetAction, "0x" + strings.ToUpper(alice[2:]), "0", "2"},
+		{"increase", IncreaseAction, bob, "1.5", "7.5"},
+		{"reduce", ReduceAction, alice, "3", "3"},
+		{"reduce below zero is clamped", ReduceAction, bob, "5", "4"},
+		{"reduce of a beneficiary without allowance", ReduceAction, ownerAddress, "1", "6"},
+		{"unknown action", "get", alice, "1", "6"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := budget.After(test.action, test.beneficiary, wei(t, test.amount))
+			if got.Cmp(wei(t, test.want)) != 0 {
+				t.Errorf("After(%s, %s) = %s, want %s", test.action, test.amount, FormatEther(got), test.want)
+			}
+		})
+	}
+	if budget.Allowances.Cmp(wei(t, "6")) != 0 || budget.Beneficiaries[1].Allowance.Cmp(wei(t, "2")) != 0 {
+		t.Error("After changed the budget")
+	}
+}
+
+func TestBudgetExceeds(t *testing.T) {
+	budget := Budget{Balance: wei(t, "10")}
+	tests := []struct {
+		allowances string
+		limit      float64
+		want       bool
+	}{
+		{"15", 0, false},
+		{"15", -1, false},
+		{"10", 1, false},
+		{"10.000000000000000001", 1, true},
+		{"15", 1.5, false},
+		{"15.1", 1.5, true},
+		{"4", 0.5, false},
+		{"6", 0.5, true},
+	}
+	for _, test := range tests {
+		if got := budget.Exceeds(wei(t, test.allowances), test.limit); got != test.want {
+			t.Errorf("Exceeds(%s, %g) = %t, want %t", test.allowances, test.limit, got, test.want)
+		}
+	}
+}
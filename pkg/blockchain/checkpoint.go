@@ -0,0 +1,121 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// checkpointFlushInterval minimum time between two checkpoint file writes
+const checkpointFlushInterval = time.Second
+
+// Position of a log in the blockchain
+type Position struct {
+	Block    uint64 `json:"block"`
+	LogIndex uint   `json:"log_index"`
+}
+
+// After reports whether the position is after the given one
+func (p Position) After(other Position) bool {
+	if p.Block != other.Block {
+		return p.Block > other.Block
+	}
+	return p.LogIndex > other.LogIndex
+}
+
+// Checkpoint interface
+type Checkpoint interface {
+	Get(stream string) (Position, bool)
+	Set(stream string, position Position)
+	Flush() error
+}
+
+type checkpoint struct {
+	filename  string
+	mutex     sync.Mutex
+	positions map[string]Position
+	dirty     bool
+	flushedAt time.Time
+}
+
+// NewCheckpoint returns a checkpoint persisted in the given file, it is only kept in memory if the filename is empty
+func NewCheckpoint(filename string) (Checkpoint, error) {
+	c := &checkpoint{
+		filename:  filename,
+		positions: map[string]Position{},
+	}
+	if filename == "" {
+		return c, nil
+	}
+	content, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(content, &c.positions); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns the last processed position of a stream
+func (c *checkpoint) Get(stream string) (Position, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	position, ok := c.positions[stream]
+	return position, ok
+}
+
+// Set updates the last processed position of a stream, the file is written at most once per flush interval
+func (c *checkpoint) Set(stream string, position Position) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.positions[stream] = position
+	c.dirty = true
+	if time.Since(c.flushedAt) < checkpointFlushInterval {
+		return
+	}
+	_ = c.write()
+}
+
+// Flush writes the pending positions into the file
+func (c *checkpoint) Flush() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.write()
+}
+
+// write replaces the checkpoint file atomically, the caller should hold the mutex
+func (c *checkpoint) write() error {
+	if c.filename == "" || !c.dirty {
+		return nil
+	}
+	content, err := json.MarshalIndent(c.positions, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.filename), filepath.Base(c.filename)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), c.filename); err != nil {
+		return err
+	}
+	c.dirty = false
+	c.flushedAt = time.Now()
+	return nil
+}
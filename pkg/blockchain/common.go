@@ -13,12 +13,14 @@ import (
 	"github.com/ethereum/go-ethereum/params"
 	"math/big"
 	"regexp"
+	"strings"
 )
 
 var (
 	ErrInvalidKey = errors.New("invalid key")
 	ErrInvalidAddress = errors.New("invalid address")
 	ErrInvalidContractAddress = errors.New("invalid contract address")
+	ErrInvalidAmount = errors.New("invalid ether amount")
 )
 
 // getSigner get the signer for sign transactions
@@ -97,7 +99,25 @@ func etherToWei(eth *big.Int) *big.Int {
 	return new(big.Int).Mul(eth, big.NewInt(params.Ether))
 }
 
-// weiToEther convert Wei to Ether
-func weiToEther(wei *big.Int) *big.Int {
-	return new(big.Int).Div(wei, big.NewInt(params.Ether))
-}
\ No newline at end of file
+// ParseEther converts a decimal ether amount, i.e. 1.5, to wei
+func ParseEther(value string) (*big.Int, error) {
+	amount, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return nil, ErrInvalidAmount
+	}
+	amount.Mul(amount, new(big.Rat).SetInt(big.NewInt(params.Ether)))
+	if !amount.IsInt() {
+		return nil, ErrInvalidAmount
+	}
+	return new(big.Int).Set(amount.Num()), nil
+}
+
+// FormatEther converts a wei amount to a decimal ether amount without losing precision
+func FormatEther(wei *big.Int) string {
+	if wei == nil {
+		return "0"
+	}
+	value := new(big.Rat).SetFrac(wei, big.NewInt(params.Ether)).FloatString(18)
+	value = strings.TrimRight(value, "0")
+	return strings.TrimSuffix(value, ".")
+}
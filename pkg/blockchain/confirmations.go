@@ -0,0 +1,112 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/core/types"
+	"sync"
+	"time"
+)
+
+// headPollInterval time between two head requests while there are events waiting for confirmations
+const headPollInterval = 2 * time.Second
+
+// pendingEvent event waiting for confirmations
+type pendingEvent struct {
+	stream string
+	raw    types.Log
+	event  Event
+}
+
+// confirmer holds the events until there are enough blocks mined on top of them
+type confirmer struct {
+	confirmations uint64
+	emit          func(ctx context.Context, stream string, raw types.Log, event Event)
+	mutex         sync.Mutex
+	head          uint64
+	pending       []pendingEvent
+}
+
+func newConfirmer(confirmations uint64, emit func(ctx context.Context, stream string, raw types.Log, event Event)) *confirmer {
+	return &confirmer{
+		confirmations: confirmations,
+		emit:          emit,
+	}
+}
+
+// add queues the event, it is emitted right away when no confirmations are required
+func (c *confirmer) add(ctx context.Context, stream string, raw types.Log, event Event) {
+	if c.confirmations == 0 {
+		c.emit(ctx, stream, raw, event)
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, p := range c.pending {
+		if p.stream == stream && p.raw.BlockNumber == raw.BlockNumber && p.raw.Index == raw.Index {
+			return
+		}
+	}
+	c.pending = append(c.pending, pendingEvent{stream: stream, raw: raw, event: event})
+	if raw.BlockNumber > c.head {
+		c.head = raw.BlockNumber
+	}
+	c.release(ctx)
+}
+
+// drop removes a pending event, it returns false if the event is not pending
+func (c *confirmer) drop(stream string, raw types.Log) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i, p := range c.pending {
+		if p.stream == stream && p.raw.BlockHash == raw.BlockHash && p.raw.Index == raw.Index {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// setHead updates the current head and emits the confirmed events
+func (c *confirmer) setHead(ctx context.Context, head uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if head > c.head {
+		c.head = head
+	}
+	c.release(ctx)
+}
+
+// release emits the confirmed events keeping their arrival order, the caller should hold the mutex
+func (c *confirmer) release(ctx context.Context) {
+	remaining := c.pending[:0]
+	for _, p := range c.pending {
+		if p.raw.BlockNumber+c.confirmations <= c.head {
+			c.emit(ctx, p.stream, p.raw, p.event)
+			continue
+		}
+		remaining = append(remaining, p)
+	}
+	c.pending = remaining
+}
+
+// trackHead polls the head of the chain until the context is done
+func (c *confirmer) trackHead(ctx context.Context, conn *connection) error {
+	if c.confirmations == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(headPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			client, _ := conn.get()
+			head, err := client.BlockNumber(ctx)
+			if err != nil {
+				continue
+			}
+			c.setHead(ctx, head)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/StevenRojas/sharedWallet/pkg/metrics"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// connection shares a client between the watchers and dials it again when it drops
+type connection struct {
+	endpoint   string
+	mutex      sync.Mutex
+	client     *ethclient.Client
+	generation int
+	reconnects int
+}
+
+func newConnection(endpoint string, client *ethclient.Client) *connection {
+	return &connection{
+		endpoint: endpoint,
+		client:   client,
+	}
+}
+
+// get returns the current client and its generation
+func (c *connection) get() (*ethclient.Client, int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.client, c.generation
+}
+
+// reconnect dials a new client with exponential backoff. When the given generation is not the current one
+// another watcher already reconnected, so the current client is returned
+func (c *connection) reconnect(ctx context.Context, generation int) (*ethclient.Client, int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if generation != c.generation {
+		return c.client, c.generation, nil
+	}
+
+	c.client.Close()
+	backoff := minReconnectBackoff
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(backoff):
+		}
+		client, err := ethclient.DialContext(ctx, c.endpoint)
+		if err == nil {
+			if _, err = client.BlockNumber(ctx); err == nil {
+				c.client = client
+				c.generation++
+				c.reconnects++
+				metrics.Reconnects.WithLabelValues(c.endpoint).Inc()
+				log.Printf("reconnected to %s (%d reconnects)\n", c.endpoint, c.reconnects)
+				return c.client, c.generation, nil
+			}
+			client.Close()
+		}
+		log.Printf("unable to reconnect to %s: %s\n", c.endpoint, err.Error())
+		backoff *= 2
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
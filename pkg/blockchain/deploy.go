@@ -13,6 +13,7 @@ import (
 type Deployer interface {
 	Deploy(ctx context.Context, client *ethclient.Client) error
 	ContractAddress() string
+	TxHash() string
 }
 
 type deployer struct {
@@ -49,4 +50,12 @@ func (d *deployer) Deploy(ctx context.Context, client *ethclient.Client) error {
 // ContractAddress returns the contract address
 func (d *deployer) ContractAddress() string {
 	return d.address.Hex()
-}
\ No newline at end of file
+}
+
+// TxHash returns the hash of the deployment transaction
+func (d *deployer) TxHash() string {
+	if d.transaction == nil {
+		return ""
+	}
+	return d.transaction.Hash().Hex()
+}
@@ -0,0 +1,245 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	contracts "github.com/StevenRojas/sharedWallet/contracts/interfaces"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	AllowanceChanged = "AllowanceChanged"
+	MoneySent = "MoneySent"
+	MoneyReceived = "MoneyReceived"
+	OwnershipTransferred = "OwnershipTransferred"
+	Retracted = "Retracted"
+)
+
+// eventIDs maps the event types to the topic of their logs
+var eventIDs = func() map[string]common.Hash {
+	parsed, err := abi.JSON(strings.NewReader(contracts.ContractABI))
+	if err != nil {
+		panic(err)
+	}
+	ids := map[string]common.Hash{}
+	for _, eventType := range eventTypes {
+		ids[eventType] = parsed.Events[eventType].ID
+	}
+	return ids
+}()
+
+// Event interface implemented by the monitor events
+type Event interface {
+	Type() string
+	Log() EventLog
+	setLog(eventLog EventLog)
+}
+
+// EventSink interface, it receives the events emitted by the monitor
+type EventSink interface {
+	Write(ctx context.Context, event Event) error
+}
+
+// EventFlusher interface implemented by the sinks that acknowledge the events, Flush returns once the events written
+// before it are delivered. The monitor only moves the checkpoint past the acknowledged events
+type EventFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// EventLog struct with the location of the event in the blockchain
+type EventLog struct {
+	Contract string `json:"contract"`
+	Label string `json:"label,omitempty"`
+	BlockNumber uint64 `json:"block_number"`
+	BlockHash string `json:"block_hash"`
+	TxHash string `json:"tx_hash"`
+	LogIndex uint `json:"log_index"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Log returns the event location
+func (l *EventLog) Log() EventLog {
+	return *l
+}
+
+func (l *EventLog) setLog(eventLog EventLog) {
+	*l = eventLog
+}
+
+// newEventLog returns the location of a raw log, the timestamp is the block time
+func newEventLog(raw types.Log, blockTime uint64) EventLog {
+	return EventLog{
+		Contract:    raw.Address.Hex(),
+		BlockNumber: raw.BlockNumber,
+		BlockHash:   raw.BlockHash.Hex(),
+		TxHash:      raw.TxHash.Hex(),
+		LogIndex:    raw.Index,
+		Timestamp:   time.Unix(int64(blockTime), 0),
+	}
+}
+
+// AllowanceChangedEvent struct
+type AllowanceChangedEvent struct {
+	Event string `json:"event_type"`
+	Sender string `json:"sender"`
+	Beneficiary string `json:"beneficiary"`
+	PrevAmount *big.Int `json:"prev_amount"`
+	NewAmount *big.Int `json:"new_amount"`
+	EventLog
+}
+
+// Type returns the event type
+func (e *AllowanceChangedEvent) Type() string {
+	return e.Event
+}
+
+// MoneyReceivedEvent struct
+type MoneyReceivedEvent struct {
+	Event string `json:"event_type"`
+	Sender string `json:"sender"`
+	Amount *big.Int `json:"amount"`
+	EventLog
+}
+
+// Type returns the event type
+func (e *MoneyReceivedEvent) Type() string {
+	return e.Event
+}
+
+// MoneySentEvent struct
+type MoneySentEvent struct {
+	Event string `json:"event_type"`
+	Beneficiary string `json:"beneficiary"`
+	Amount *big.Int `json:"amount"`
+	EventLog
+}
+
+// Type returns the event type
+func (e *MoneySentEvent) Type() string {
+	return e.Event
+}
+
+// OwnershipTransferredEvent struct
+type OwnershipTransferredEvent struct {
+	Event string `json:"event_type"`
+	PreviousOwner string `json:"previous_owner"`
+	NewOwner string `json:"new_owner"`
+	EventLog
+}
+
+// Type returns the event type
+func (e *OwnershipTransferredEvent) Type() string {
+	return e.Event
+}
+
+// RetractedEvent struct, emitted when an already emitted event is removed by a chain reorganization
+type RetractedEvent struct {
+	Event string `json:"event_type"`
+	Retracted Event `json:"retracted"`
+	EventLog
+}
+
+// Type returns the event type
+func (e *RetractedEvent) Type() string {
+	return e.Event
+}
+
+// UnmarshalEvent decodes a JSON event into the struct of its event type
+func UnmarshalEvent(data []byte) (Event, error) {
+	var header struct {
+		Event string `json:"event_type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	var event Event
+	switch header.Event {
+	case AllowanceChanged:
+		event = &AllowanceChangedEvent{}
+	case MoneySent:
+		event = &MoneySentEvent{}
+	case MoneyReceived:
+		event = &MoneyReceivedEvent{}
+	case OwnershipTransferred:
+		event = &OwnershipTransferredEvent{}
+	default:
+		return nil, ErrInvalidEventType
+	}
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// decodeEvent dispatch the log to the contract parser of its topic, it returns nil for unknown logs
+func decodeEvent(contract *contracts.ContractFilterer, raw types.Log) (Event, error) {
+	if len(raw.Topics) == 0 {
+		return nil, nil
+	}
+	switch raw.Topics[0] {
+	case eventIDs[AllowanceChanged]:
+		event, err := contract.ParseAllowanceChanged(raw)
+		if err != nil {
+			return nil, err
+		}
+		return newAllowanceChangedEvent(event), nil
+	case eventIDs[MoneySent]:
+		event, err := contract.ParseMoneySent(raw)
+		if err != nil {
+			return nil, err
+		}
+		return newMoneySentEvent(event), nil
+	case eventIDs[MoneyReceived]:
+		event, err := contract.ParseMoneyReceived(raw)
+		if err != nil {
+			return nil, err
+		}
+		return newMoneyReceivedEvent(event), nil
+	case eventIDs[OwnershipTransferred]:
+		event, err := contract.ParseOwnershipTransferred(raw)
+		if err != nil {
+			return nil, err
+		}
+		return newOwnershipTransferredEvent(event), nil
+	}
+	return nil, nil
+}
+
+func newAllowanceChangedEvent(event *contracts.ContractAllowanceChanged) *AllowanceChangedEvent {
+	return &AllowanceChangedEvent{
+		Event:       AllowanceChanged,
+		Sender:      event.Sender.Hex(),
+		Beneficiary: event.Beneficiary.Hex(),
+		PrevAmount:  event.PrevAmount,
+		NewAmount:   event.NewAmount,
+	}
+}
+
+func newMoneySentEvent(event *contracts.ContractMoneySent) *MoneySentEvent {
+	return &MoneySentEvent{
+		Event:       MoneySent,
+		Beneficiary: event.Beneficiary.Hex(),
+		Amount:      event.Amount,
+	}
+}
+
+func newMoneyReceivedEvent(event *contracts.ContractMoneyReceived) *MoneyReceivedEvent {
+	return &MoneyReceivedEvent{
+		Event:  MoneyReceived,
+		Sender: event.From.Hex(),
+		Amount: event.Amount,
+	}
+}
+
+func newOwnershipTransferredEvent(event *contracts.ContractOwnershipTransferred) *OwnershipTransferredEvent {
+	return &OwnershipTransferredEvent{
+		Event:         OwnershipTransferred,
+		PreviousOwner: event.PreviousOwner.Hex(),
+		NewOwner:      event.NewOwner.Hex(),
+	}
+}
@@ -0,0 +1,169 @@
+package blockchain
+
+import (
+	"errors"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidEventType = errors.New("invalid event type")
+
+// eventTypes event types emitted by the contract
+var eventTypes = []string{AllowanceChanged, MoneySent, MoneyReceived, OwnershipTransferred}
+
+// EventFilter struct, the address lists match the indexed event arguments
+type EventFilter struct {
+	// Events event types to watch, all of them if empty
+	Events map[string]struct{}
+	// Beneficiaries filter AllowanceChanged and MoneySent events
+	Beneficiaries []common.Address
+	// Senders filter AllowanceChanged events
+	Senders []common.Address
+	// From filter MoneyReceived events
+	From []common.Address
+	// MinAmount filter MoneySent and MoneyReceived events with a lower amount in wei
+	MinAmount *big.Int
+}
+
+// NewEventFilter returns a filter validating the event types and addresses
+func NewEventFilter(events []string, beneficiaries []string, senders []string, from []string, minAmount int64) (EventFilter, error) {
+	filter := EventFilter{}
+	if len(events) > 0 {
+		filter.Events = map[string]struct{}{}
+	}
+	for _, event := range events {
+		eventType, ok := findEventType(event)
+		if !ok {
+			return filter, ErrInvalidEventType
+		}
+		filter.Events[eventType] = struct{}{}
+	}
+	var err error
+	if filter.Beneficiaries, err = toAddresses(beneficiaries); err != nil {
+		return filter, err
+	}
+	if filter.Senders, err = toAddresses(senders); err != nil {
+		return filter, err
+	}
+	if filter.From, err = toAddresses(from); err != nil {
+		return filter, err
+	}
+	if minAmount > 0 {
+		filter.MinAmount = etherToWei(big.NewInt(minAmount))
+	}
+	return filter, nil
+}
+
+// watches reports whether the event type should be watched
+func (f EventFilter) watches(eventType string) bool {
+	if len(f.Events) == 0 {
+		return true
+	}
+	_, ok := f.Events[eventType]
+	return ok
+}
+
+// topics returns the log topics requested to the node: the watched event types and, when every watched event
+// has an address filter in the same position, the filtered addresses. The rest of the filters are applied by accepts
+func (f EventFilter) topics() [][]common.Hash {
+	var ids, first, second []common.Hash
+	narrowFirst, narrowSecond := true, true
+	for _, eventType := range eventTypes {
+		if !f.watches(eventType) {
+			continue
+		}
+		ids = append(ids, eventIDs[eventType])
+		firstAddresses, secondAddresses := f.indexed(eventType)
+		narrowFirst = narrowFirst && len(firstAddresses) > 0
+		narrowSecond = narrowSecond && len(secondAddresses) > 0
+		first = append(first, toTopics(firstAddresses)...)
+		second = append(second, toTopics(secondAddresses)...)
+	}
+	topics := [][]common.Hash{ids}
+	if narrowFirst {
+		topics = append(topics, first)
+	}
+	if narrowSecond {
+		if !narrowFirst {
+			topics = append(topics, nil)
+		}
+		topics = append(topics, second)
+	}
+	return topics
+}
+
+// indexed returns the address filters of the first and second indexed arguments of an event type
+func (f EventFilter) indexed(eventType string) ([]common.Address, []common.Address) {
+	switch eventType {
+	case AllowanceChanged:
+		return f.Beneficiaries, f.Senders
+	case MoneySent:
+		return f.Beneficiaries, nil
+	case MoneyReceived:
+		return f.From, nil
+	}
+	return nil, nil
+}
+
+// accepts reports whether the event matches the address filters and reaches the minimum amount
+func (f EventFilter) accepts(event Event) bool {
+	switch e := event.(type) {
+	case *AllowanceChangedEvent:
+		return containsAddress(f.Beneficiaries, e.Beneficiary) && containsAddress(f.Senders, e.Sender)
+	case *MoneySentEvent:
+		return containsAddress(f.Beneficiaries, e.Beneficiary) && reaches(e.Amount, f.MinAmount)
+	case *MoneyReceivedEvent:
+		return containsAddress(f.From, e.Sender) && reaches(e.Amount, f.MinAmount)
+	}
+	return true
+}
+
+// containsAddress reports whether the address is in the list, an empty list contains every address
+func containsAddress(addresses []common.Address, address string) bool {
+	if len(addresses) == 0 {
+		return true
+	}
+	for _, a := range addresses {
+		if a == common.HexToAddress(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// reaches reports whether the amount reaches the minimum, a nil minimum is always reached
+func reaches(amount *big.Int, minimum *big.Int) bool {
+	return minimum == nil || amount.Cmp(minimum) >= 0
+}
+
+// toTopics converts addresses into topics
+func toTopics(addresses []common.Address) []common.Hash {
+	topics := make([]common.Hash, 0, len(addresses))
+	for _, address := range addresses {
+		topics = append(topics, common.BytesToHash(address.Bytes()))
+	}
+	return topics
+}
+
+// findEventType returns the event type matching the name without case sensitivity
+func findEventType(name string) (string, bool) {
+	for _, eventType := range eventTypes {
+		if strings.EqualFold(eventType, strings.TrimSpace(name)) {
+			return eventType, true
+		}
+	}
+	return "", false
+}
+
+// toAddresses validates and converts a list of addresses
+func toAddresses(addresses []string) ([]common.Address, error) {
+	var result []common.Address
+	for _, address := range addresses {
+		if err := validateAddress(address); err != nil {
+			return nil, err
+		}
+		result = append(result, common.HexToAddress(address))
+	}
+	return result, nil
+}
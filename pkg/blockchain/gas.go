@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+)
+
+// GasCost struct, the fee in wei paid by the sender of a transaction
+type GasCost struct {
+	TxHash string `json:"tx_hash"`
+	Payer string `json:"payer"`
+	Gas uint64 `json:"gas"`
+	Fee *big.Int `json:"fee"`
+}
+
+// GasCosts reads the receipts of the transactions and returns their fees by transaction hash
+func GasCosts(ctx context.Context, client *ethclient.Client, txHashes []string) (map[string]GasCost, error) {
+	costs := make(map[string]GasCost, len(txHashes))
+	baseFees := map[common.Hash]*big.Int{}
+	for _, txHash := range txHashes {
+		if _, ok := costs[txHash]; ok {
+			continue
+		}
+		hash := common.HexToHash(txHash)
+		tx, _, err := client.TransactionByHash(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		receipt, err := client.TransactionReceipt(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		payer, err := client.TransactionSender(ctx, tx, receipt.BlockHash, receipt.TransactionIndex)
+		if err != nil {
+			return nil, err
+		}
+		baseFee, ok := baseFees[receipt.BlockHash]
+		if !ok {
+			header, err := client.HeaderByHash(ctx, receipt.BlockHash)
+			if err != nil {
+				return nil, err
+			}
+			baseFee = header.BaseFee
+			baseFees[receipt.BlockHash] = baseFee
+		}
+		costs[txHash] = GasCost{
+			TxHash: txHash,
+			Payer: payer.Hex(),
+			Gas: receipt.GasUsed,
+			Fee: new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), effectiveGasPrice(tx, baseFee)),
+		}
+	}
+	return costs, nil
+}
+
+// effectiveGasPrice returns the price paid by gas unit, the dynamic fee transactions pay the block base fee plus their
+// tip up to their fee cap
+func effectiveGasPrice(tx *types.Transaction, baseFee *big.Int) *big.Int {
+	if tx.Type() != types.DynamicFeeTxType || baseFee == nil {
+		return tx.GasPrice()
+	}
+	price := new(big.Int).Add(baseFee, tx.GasTipCap())
+	if price.Cmp(tx.GasFeeCap()) > 0 {
+		return tx.GasFeeCap()
+	}
+	return price
+}
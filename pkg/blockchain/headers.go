@@ -0,0 +1,51 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"sync"
+)
+
+// headerCacheSize number of block times kept by the cache
+const headerCacheSize = 1024
+
+// headerCache keeps the time of the latest requested blocks, the events of the same block share the header request
+type headerCache struct {
+	mutex sync.Mutex
+	times map[common.Hash]uint64
+	order []common.Hash
+}
+
+func newHeaderCache() *headerCache {
+	return &headerCache{
+		times: make(map[common.Hash]uint64, headerCacheSize),
+	}
+}
+
+// blockTime returns the time of the block with the given hash, the oldest block is evicted when the cache is full
+func (c *headerCache) blockTime(ctx context.Context, client *ethclient.Client, hash common.Hash) (uint64, error) {
+	c.mutex.Lock()
+	blockTime, ok := c.times[hash]
+	c.mutex.Unlock()
+	if ok {
+		return blockTime, nil
+	}
+
+	header, err := client.HeaderByHash(ctx, hash)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok = c.times[hash]; !ok {
+		if len(c.order) == headerCacheSize {
+			delete(c.times, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.times[hash] = header.Time
+		c.order = append(c.order, hash)
+	}
+	return header.Time, nil
+}
@@ -0,0 +1,197 @@
+package blockchain
+
+import (
+	"context"
+	contracts "github.com/StevenRojas/sharedWallet/contracts/interfaces"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Record struct, a contract event read from the history with its amounts in wei. The sender is the owner of the
+// allowance changes and the funder of the received money, the previous and new owners are the sender and beneficiary
+// of the ownership transfers. The amount is the new allowance of the allowance changes
+type Record struct {
+	Type        string   `json:"event_type"`
+	Sender      string   `json:"sender,omitempty"`
+	Beneficiary string   `json:"beneficiary,omitempty"`
+	PrevAmount  *big.Int `json:"prev_amount,omitempty"`
+	Amount      *big.Int `json:"amount,omitempty"`
+	EventLog
+}
+
+// Position returns the position of the record in the chain
+func (r Record) Position() Position {
+	return Position{Block: r.BlockNumber, LogIndex: r.LogIndex}
+}
+
+// HistoryQuery struct, the empty fields are not used to filter
+type HistoryQuery struct {
+	// Types event types to read, all of them if empty
+	Types []string
+	// Beneficiaries of the AllowanceChanged and MoneySent events
+	Beneficiaries []string
+	// Senders of the MoneyReceived events
+	Senders []string
+	FromBlock uint64
+	// ToBlock last block to read, the latest one if nil
+	ToBlock *uint64
+	// PageSize number of blocks requested on every page
+	PageSize uint64
+}
+
+// History interface, it reads the contract events through the generated filter iterators
+type History interface {
+	Records(ctx context.Context, query HistoryQuery) ([]Record, error)
+}
+
+type history struct {
+	contractAddress string
+	client *ethclient.Client
+	contract *contracts.ContractFilterer
+	headers *headerCache
+}
+
+// NewHistory returns a reader of the contract history
+func NewHistory(client *ethclient.Client, contractAddress string) (History, error) {
+	contract, err := contracts.NewContractFilterer(common.HexToAddress(contractAddress), client)
+	if err != nil {
+		return nil, err
+	}
+	return &history{
+		contractAddress: contractAddress,
+		client: client,
+		contract: contract,
+		headers: newHeaderCache(),
+	}, nil
+}
+
+// Records returns the matching events sorted by block and log index, the blocks are requested in pages
+func (h *history) Records(ctx context.Context, query HistoryQuery) ([]Record, error) {
+	if err := validateContractAddress(ctx, h.client, h.contractAddress); err != nil {
+		return nil, err
+	}
+	if query.PageSize == 0 {
+		query.PageSize = DefaultPageSize
+	}
+	toBlock := query.ToBlock
+	if toBlock == nil {
+		head, err := h.client.BlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		toBlock = &head
+	}
+	watched := query.Types
+	if len(watched) == 0 {
+		watched = eventTypes
+	}
+	beneficiaries, err := toAddresses(query.Beneficiaries)
+	if err != nil {
+		return nil, err
+	}
+	senders, err := toAddresses(query.Senders)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for start := query.FromBlock; start <= *toBlock; start += query.PageSize {
+		end := start + query.PageSize - 1
+		if end > *toBlock {
+			end = *toBlock
+		}
+		opts := &bind.FilterOpts{Start: start, End: &end, Context: ctx}
+		for _, eventType := range watched {
+			page, err := h.page(opts, eventType, beneficiaries, senders)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, page...)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[j].Position().After(records[i].Position())
+	})
+	for i := range records {
+		blockTime, err := h.headers.blockTime(ctx, h.client, common.HexToHash(records[i].BlockHash))
+		if err != nil {
+			return nil, err
+		}
+		records[i].Timestamp = time.Unix(int64(blockTime), 0)
+	}
+	return records, nil
+}
+
+// page reads the events of a type in the blocks of the filter options
+func (h *history) page(opts *bind.FilterOpts, eventType string, beneficiaries []common.Address, senders []common.Address) ([]Record, error) {
+	var records []Record
+	switch eventType {
+	case AllowanceChanged:
+		iterator, err := h.contract.FilterAllowanceChanged(opts, beneficiaries, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer iterator.Close()
+		for iterator.Next() {
+			e := iterator.Event
+			records = append(records, newRecord(AllowanceChanged, e.Raw, e.Sender, e.Beneficiary, e.PrevAmount, e.NewAmount))
+		}
+		return records, iterator.Error()
+	case MoneySent:
+		iterator, err := h.contract.FilterMoneySent(opts, beneficiaries)
+		if err != nil {
+			return nil, err
+		}
+		defer iterator.Close()
+		for iterator.Next() {
+			e := iterator.Event
+			records = append(records, newRecord(MoneySent, e.Raw, common.Address{}, e.Beneficiary, nil, e.Amount))
+		}
+		return records, iterator.Error()
+	case MoneyReceived:
+		iterator, err := h.contract.FilterMoneyReceived(opts, senders)
+		if err != nil {
+			return nil, err
+		}
+		defer iterator.Close()
+		for iterator.Next() {
+			e := iterator.Event
+			records = append(records, newRecord(MoneyReceived, e.Raw, e.From, common.Address{}, nil, e.Amount))
+		}
+		return records, iterator.Error()
+	case OwnershipTransferred:
+		iterator, err := h.contract.FilterOwnershipTransferred(opts, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer iterator.Close()
+		for iterator.Next() {
+			e := iterator.Event
+			records = append(records, newRecord(OwnershipTransferred, e.Raw, e.PreviousOwner, e.NewOwner, nil, nil))
+		}
+		return records, iterator.Error()
+	}
+	return nil, ErrInvalidEventType
+}
+
+// newRecord returns a record, the empty addresses are left blank
+func newRecord(eventType string, raw types.Log, sender common.Address, beneficiary common.Address, prevAmount *big.Int, amount *big.Int) Record {
+	record := Record{
+		Type:       eventType,
+		PrevAmount: prevAmount,
+		Amount:     amount,
+		EventLog:   newEventLog(raw, 0),
+	}
+	if sender != (common.Address{}) || eventType == OwnershipTransferred {
+		record.Sender = sender.Hex()
+	}
+	if beneficiary != (common.Address{}) {
+		record.Beneficiary = beneficiary.Hex()
+	}
+	return record
+}
@@ -0,0 +1,61 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"io/ioutil"
+	"strings"
+)
+
+var ErrKeyNotOwner = errors.New("the recovered key does not belong to the contract owner")
+
+// PrivateKeyBytes decode a hex private key validating it is a valid secp256k1 key
+func PrivateKeyBytes(privateKey string) ([]byte, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(privateKey, "0x"))
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return crypto.FromECDSA(key), nil
+}
+
+// KeyAddress returns the address derived from a private key
+func KeyAddress(privateKey []byte) (string, error) {
+	key, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return "", ErrInvalidKey
+	}
+	return crypto.PubkeyToAddress(key.PublicKey).Hex(), nil
+}
+
+// KeystorePrivateKey decrypt a keystore file and returns its hex private key
+func KeystorePrivateKey(path string, passphrase string) (string, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	key, err := keystore.DecryptKey(content, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(crypto.FromECDSA(key.PrivateKey)), nil
+}
+
+// VerifyOwnerKey checks the private key derived address matches the contract owner
+func VerifyOwnerKey(ctx context.Context, client *ethclient.Client, contractAddress string, privateKey []byte) (string, error) {
+	address, err := KeyAddress(privateKey)
+	if err != nil {
+		return "", err
+	}
+	owner, err := NewOwnerRunner("", contractAddress).GetOwner(ctx, client, BlockRef{})
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(owner, address) {
+		return address, ErrKeyNotOwner
+	}
+	return address, nil
+}
@@ -0,0 +1,131 @@
+package blockchain
+
+import (
+	"github.com/StevenRojas/sharedWallet/pkg/price"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"time"
+)
+
+const (
+	LedgerGrant     = "grant"
+	LedgerIncrease  = "increase"
+	LedgerReduction = "reduction"
+	LedgerPayout    = "payout"
+)
+
+// LedgerEntry struct, a line of the beneficiary statement with its amounts in wei
+type LedgerEntry struct {
+	Time      time.Time `json:"time"`
+	Block     uint64    `json:"block"`
+	TxHash    string    `json:"tx_hash"`
+	Kind      string    `json:"kind"`
+	Amount    *big.Int  `json:"amount"`
+	Allowance *big.Int  `json:"allowance"`
+	// Value fiat value of the amount at the entry time
+	Value *price.Value `json:"value,omitempty"`
+}
+
+// Ledger struct, the chronological statement of a beneficiary with its totals in wei
+type Ledger struct {
+	Beneficiary string        `json:"beneficiary"`
+	Entries     []LedgerEntry `json:"entries"`
+	Granted     *big.Int      `json:"granted"`
+	Reduced     *big.Int      `json:"reduced"`
+	PaidOut     *big.Int      `json:"paid_out"`
+	Allowance   *big.Int      `json:"allowance"`
+	// PaidOutValue fiat value of the payouts at their times
+	PaidOutValue *price.Value `json:"paid_out_value,omitempty"`
+}
+
+// NewLedger builds the statement of a beneficiary from its AllowanceChanged and MoneySent records. The sendMoney
+// allowance reduction and its MoneySent event are a single payout, the allowance is the one of the events
+func NewLedger(beneficiary string, records []Record) Ledger {
+	ledger := Ledger{
+		Beneficiary: common.HexToAddress(beneficiary).Hex(),
+		Granted:     new(big.Int),
+		Reduced:     new(big.Int),
+		PaidOut:     new(big.Int),
+		Allowance:   new(big.Int),
+	}
+	for _, record := range records {
+		if common.HexToAddress(record.Beneficiary) != common.HexToAddress(beneficiary) {
+			continue
+		}
+		switch record.Type {
+		case AllowanceChanged:
+			change := new(big.Int).Sub(record.Amount, record.PrevAmount)
+			if change.Sign() == 0 {
+				continue
+			}
+			kind := LedgerIncrease
+			switch {
+			case change.Sign() < 0:
+				kind = LedgerReduction
+				change.Neg(change)
+			case record.PrevAmount.Sign() == 0:
+				kind = LedgerGrant
+			}
+			ledger.Entries = append(ledger.Entries, LedgerEntry{
+				Time:      record.Timestamp,
+				Block:     record.BlockNumber,
+				TxHash:    record.TxHash,
+				Kind:      kind,
+				Amount:    change,
+				Allowance: new(big.Int).Set(record.Amount),
+			})
+			ledger.Allowance.Set(record.Amount)
+		case MoneySent:
+			ledger.payout(record)
+		}
+	}
+	for _, entry := range ledger.Entries {
+		switch entry.Kind {
+		case LedgerGrant, LedgerIncrease:
+			ledger.Granted.Add(ledger.Granted, entry.Amount)
+		case LedgerReduction:
+			ledger.Reduced.Add(ledger.Reduced, entry.Amount)
+		case LedgerPayout:
+			ledger.PaidOut.Add(ledger.PaidOut, entry.Amount)
+		}
+	}
+	return ledger
+}
+
+// Valuate sets the fiat value of the entries and payouts with the prices at the entry times
+func (l *Ledger) Valuate(prices price.Table) error {
+	l.PaidOutValue = price.NewValue()
+	for i := range l.Entries {
+		value, err := prices.Value(l.Entries[i].Amount, l.Entries[i].Time)
+		if err != nil {
+			return err
+		}
+		l.Entries[i].Value = value
+		if l.Entries[i].Kind == LedgerPayout {
+			l.PaidOutValue.Add(value)
+		}
+	}
+	return nil
+}
+
+// payout turns the reduction of the same transaction into a payout, or adds it when there is no reduction
+func (l *Ledger) payout(record Record) {
+	for i := len(l.Entries) - 1; i >= 0; i-- {
+		entry := &l.Entries[i]
+		if entry.TxHash != record.TxHash {
+			break
+		}
+		if entry.Kind == LedgerReduction && entry.Amount.Cmp(record.Amount) == 0 {
+			entry.Kind = LedgerPayout
+			return
+		}
+	}
+	l.Entries = append(l.Entries, LedgerEntry{
+		Time:      record.Timestamp,
+		Block:     record.BlockNumber,
+		TxHash:    record.TxHash,
+		Kind:      LedgerPayout,
+		Amount:    new(big.Int).Set(record.Amount),
+		Allowance: new(big.Int).Set(l.Allowance),
+	})
+}
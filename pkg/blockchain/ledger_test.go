@@ -0,0 +1,189 @@
+package blockchain
+
+import (
+	"github.com/StevenRojas/sharedWallet/pkg/price"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	ownerAddress = "0x00000000000000000000000000000000000000aa"
+	alice        = "0x00000000000000000000000000000000000000a1"
+	bob          = "0x00000000000000000000000000000000000000b0"
+)
+
+// wei parses an ether amount for the tests
+func wei(t *testing.T, ether string) *big.Int {
+	t.Helper()
+	amount, err := ParseEther(ether)
+	if err != nil {
+		t.Fatalf("ParseEther(%q): %v", ether, err)
+	}
+	return amount
+}
+
+// allowanceRecord returns an AllowanceChanged record of a transaction
+func allowanceRecord(t *testing.T, tx string, block uint64, beneficiary string, prev string, amount string) Record {
+	return Record{
+		Type:        AllowanceChanged,
+		Sender:      ownerAddress,
+		Beneficiary: beneficiary,
+		PrevAmount:  wei(t, prev),
+		Amount:      wei(t, amount),
+		EventLog:    testLog(tx, block),
+	}
+}
+
+// sentRecord returns a MoneySent record of a transaction
+func sentRecord(t *testing.T, tx string, block uint64, beneficiary string, amount string) Record {
+	return Record{Type: MoneySent, Beneficiary: beneficiary, Amount: wei(t, amount), EventLog: testLog(tx, block)}
+}
+
+// receivedRecord returns a MoneyReceived record of a transaction
+func receivedRecord(t *testing.T, tx string, block uint64, sender string, amount string) Record {
+	return Record{Type: MoneyReceived, Sender: sender, Amount: wei(t, amount), EventLog: testLog(tx, block)}
+}
+
+// testLog returns the location of a record, every block is mined a day after 2026-01-01
+func testLog(tx string, block uint64) EventLog {
+	return EventLog{
+		BlockNumber: block,
+		TxHash:      tx,
+		Timestamp:   time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC).AddDate(0, 0, int(block)),
+	}
+}
+
+func TestNewLedger(t *testing.T) {
+	type entry struct {
+		kind      string
+		amount    string
+		allowance string
+	}
+	tests := []struct {
+		name    string
+		records []Record
+		entries []entry
+		granted string
+		reduced string
+		paidOut string
+		left    string
+	}{
+		{
+			name: "payout pairs the reduction of its transaction",
+			records: []Record{
+				allowanceRecord(t, "0x1", 1, alice, "0", "10"),
+				allowanceRecord(t, "0x2", 2, alice, "10", "7"),
+				sentRecord(t, "0x2", 2, alice, "3"),
+			},
+			entries: []entry{{LedgerGrant, "10", "10"}, {LedgerPayout, "3", "7"}},
+			granted: "10", reduced: "0", paidOut: "3", left: "7",
+		},
+		{
+			name: "reduction of other transaction is not a payout",
+			records: []Record{
+				allowanceRecord(t, "0x1", 1, alice, "0", "10"),
+				allowanceRecord(t, "0x2", 2, alice, "10", "8"),
+				sentRecord(t, "0x3", 3, alice, "2"),
+			},
+			entries: []entry{{LedgerGrant, "10", "10"}, {LedgerReduction, "2", "8"}, {LedgerPayout, "2", "8"}},
+			granted: "10", reduced: "2", paidOut: "2", left: "8",
+		},
+		{
+			name: "reduction of a different amount is not paired",
+			records: []Record{
+				allowanceRecord(t, "0x1", 1, alice, "0", "10"),
+				allowanceRecord(t, "0x2", 2, alice, "10", "6"),
+				sentRecord(t, "0x2", 2, alice, "3"),
+			},
+			entries: []entry{{LedgerGrant, "10", "10"}, {LedgerReduction, "4", "6"}, {LedgerPayout, "3", "6"}},
+			granted: "10", reduced: "4", paidOut: "3", left: "6",
+		},
+		{
+			name: "owner payout without allowance change",
+			records: []Record{
+				sentRecord(t, "0x1", 1, alice, "1.5"),
+			},
+			entries: []entry{{LedgerPayout, "1.5", "0"}},
+			granted: "0", reduced: "0", paidOut: "1.5", left: "0",
+		},
+		{
+			name: "increase, unchanged allowance and other beneficiaries",
+			records: []Record{
+				allowanceRecord(t, "0x1", 1, alice, "0", "1"),
+				allowanceRecord(t, "0x2", 2, bob, "0", "50"),
+				allowanceRecord(t, "0x3", 3, "0x"+strings.ToUpper(alice[2:]), "1", "4"),
+				allowanceRecord(t, "0x4", 4, alice, "4", "4"),
+				sentRecord(t, "0x5", 5, bob, "50"),
+			},
+			entries: []entry{{LedgerGrant, "1", "1"}, {LedgerIncrease, "3", "4"}},
+			granted: "4", reduced: "0", paidOut: "0", left: "4",
+		},
+		{
+			name:    "no records",
+			granted: "0", reduced: "0", paidOut: "0", left: "0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ledger := NewLedger(alice, test.records)
+			if len(ledger.Entries) != len(test.entries) {
+				t.Fatalf("got %d entries, want %d: %+v", len(ledger.Entries), len(test.entries), ledger.Entries)
+			}
+			for i, want := range test.entries {
+				got := ledger.Entries[i]
+				if got.Kind != want.kind || got.Amount.Cmp(wei(t, want.amount)) != 0 ||
+					got.Allowance.Cmp(wei(t, want.allowance)) != 0 {
+					t.Errorf("entry %d = %s %s (allowance %s), want %s %s (allowance %s)", i, got.Kind,
+						FormatEther(got.Amount), FormatEther(got.Allowance), want.kind, want.amount, want.allowance)
+				}
+			}
+			totals := []struct {
+				name string
+				got  *big.Int
+				want string
+			}{
+				{"granted", ledger.Granted, test.granted},
+				{"reduced", ledger.Reduced, test.reduced},
+				{"paid out", ledger.PaidOut, test.paidOut},
+				{"allowance", ledger.Allowance, test.left},
+			}
+			for _, total := range totals {
+				if total.got.Cmp(wei(t, total.want)) != 0 {
+					t.Errorf("%s = %s, want %s", total.name, FormatEther(total.got), total.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLedgerValuate(t *testing.T) {
+	prices, err := price.Read(strings.NewReader("date,price\n2026-01-02,100\n2026-01-04,200\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger := NewLedger(alice, []Record{
+		allowanceRecord(t, "0x1", 1, alice, "0", "10"),
+		allowanceRecord(t, "0x2", 2, alice, "10", "9"),
+		sentRecord(t, "0x2", 2, alice, "1"),
+		sentRecord(t, "0x4", 4, alice, "0.5"),
+	})
+	if err = ledger.Valuate(prices); err != nil {
+		t.Fatalf("Valuate: %v", err)
+	}
+	want := []string{"1000.00", "100.00", "100.00"}
+	for i, entry := range ledger.Entries {
+		if got := entry.Value.String(); got != want[i] {
+			t.Errorf("entry %d value = %s, want %s", i, got, want[i])
+		}
+	}
+	if got := ledger.PaidOutValue.String(); got != "200.00" {
+		t.Errorf("paid out value = %s, want 200.00", got)
+	}
+
+	early := NewLedger(alice, []Record{sentRecord(t, "0x0", 0, alice, "1")})
+	if err = early.Valuate(prices); err == nil {
+		t.Error("Valuate without a price before the first entry should fail")
+	}
+}
@@ -0,0 +1,135 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/StevenRojas/sharedWallet/pkg/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"log"
+	"sync"
+	"time"
+)
+
+// progress keeps the last processed block and the beneficiaries seen by the monitor for its metrics
+type progress struct {
+	mutex         sync.Mutex
+	contract      string
+	lastBlock     uint64
+	logs          chan types.Log
+	beneficiaries map[common.Address]struct{}
+}
+
+func newProgress(contract string) *progress {
+	return &progress{
+		contract:      contract,
+		beneficiaries: make(map[common.Address]struct{}),
+	}
+}
+
+// processed moves the last processed block forward
+func (p *progress) processed(block uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if block > p.lastBlock {
+		p.lastBlock = block
+		metrics.LastBlock.WithLabelValues(p.contract).Set(float64(block))
+	}
+}
+
+// subscribed sets the live logs channel, nil when the subscription ends
+func (p *progress) subscribed(logs chan types.Log) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.logs = logs
+}
+
+// emitted counts the event and keeps its beneficiary to refresh its allowance
+func (p *progress) emitted(event Event) {
+	metrics.Events.WithLabelValues(p.contract, event.Type()).Inc()
+	var beneficiary string
+	switch e := event.(type) {
+	case *AllowanceChangedEvent:
+		beneficiary = e.Beneficiary
+	case *MoneySentEvent:
+		beneficiary = e.Beneficiary
+	default:
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.beneficiaries[common.HexToAddress(beneficiary)] = struct{}{}
+}
+
+// caughtUp returns the confirmed head when the subscription is live and there are no logs waiting to be processed,
+// so the blocks without events are processed too
+func (p *progress) caughtUp(head uint64, confirmations uint64) (uint64, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.logs == nil || len(p.logs) > 0 || head < confirmations {
+		return 0, false
+	}
+	return head - confirmations, true
+}
+
+// refreshMetrics updates the lag, balance and allowance gauges on every interval until the context is done
+func (m *monitor) refreshMetrics(ctx context.Context) error {
+	if m.options.MetricsRefresh == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(m.options.MetricsRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := m.refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("unable to refresh %s metrics: %s\n", m.name(), err.Error())
+			}
+		}
+	}
+}
+
+// refresh reads the head, the contract balance and the allowances of the known beneficiaries
+func (m *monitor) refresh(ctx context.Context) error {
+	client, _ := m.connection.get()
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if block, ok := m.progress.caughtUp(head, m.options.Confirmations); ok {
+		m.progress.processed(block)
+	}
+	m.progress.mutex.Lock()
+	lastBlock := m.progress.lastBlock
+	beneficiaries := make([]common.Address, 0, len(m.progress.beneficiaries))
+	for beneficiary := range m.progress.beneficiaries {
+		beneficiaries = append(beneficiaries, beneficiary)
+	}
+	m.progress.mutex.Unlock()
+
+	metrics.HeadBlock.WithLabelValues(m.contractAddress).Set(float64(head))
+	if head > lastBlock {
+		metrics.Lag.WithLabelValues(m.contractAddress).Set(float64(head - lastBlock))
+	} else {
+		metrics.Lag.WithLabelValues(m.contractAddress).Set(0)
+	}
+
+	state, err := NewContractState(client, m.contractAddress)
+	if err != nil {
+		return err
+	}
+	balance, err := state.Balance(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.ContractBalance.WithLabelValues(m.contractAddress).Set(metrics.Wei(balance))
+	for _, beneficiary := range beneficiaries {
+		amount, err := state.Allowance(ctx, beneficiary.Hex())
+		if err != nil {
+			return err
+		}
+		metrics.Allowance.WithLabelValues(m.contractAddress, beneficiary.Hex()).Set(metrics.Wei(amount))
+	}
+	return nil
+}
@@ -2,96 +2,127 @@ package blockchain
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	contracts "github.com/StevenRojas/sharedWallet/contracts/interfaces"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"golang.org/x/sync/errgroup"
 	"log"
+	"math"
 	"math/big"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// DefaultPageSize number of blocks requested on every history page
+	DefaultPageSize = 1000
+	// eventsBuffer size of the live logs channel, the logs are held while the history is replayed
+	eventsBuffer = 256
+)
+
 // Monitor interface
 type Monitor interface {
 	Start(ctx context.Context, client *ethclient.Client) error
 }
 
-type monitor struct {
-	contractAddress string
+// MonitorOptions struct
+type MonitorOptions struct {
+	// FromBlock first block to replay events from, nil to watch only new events
+	FromBlock *uint64
+	// PageSize number of blocks requested on every history page
+	PageSize uint64
+	// Checkpoint keeps the last processed position of the contract events, the monitor resumes from it
+	Checkpoint Checkpoint
+	// Endpoint address dialed again when the subscription or the polling fails, the monitor stops on errors if empty
+	Endpoint string
+	// PollInterval time between two log requests, when it is set the logs are polled instead of subscribing to them
+	PollInterval time.Duration
+	// Confirmations number of blocks mined on top of an event block before emitting it
+	Confirmations uint64
+	// Filter event types, addresses and amounts to watch
+	Filter EventFilter
+	// Sink receives the emitted events
+	Sink EventSink
+	// Label name of the contract added to its events and logs
+	Label string
+	// MetricsRefresh time between two refreshes of the lag, balance and allowance metrics, disabled if zero
+	MetricsRefresh time.Duration
 }
 
-// AllowanceChangedEvent struct
-type AllowanceChangedEvent struct {
-	Event string `json:"event_type"`
-	Sender string `json:"sender"`
-	Beneficiary string `json:"beneficiary"`
-	PrevAmount *big.Int `json:"prev_amount"`
-	NewAmount *big.Int `json:"new_amount"`
-	Timestamp time.Time `json:"timestamp"`
-}
-
-// MoneyReceivedEvent struct
-type MoneyReceivedEvent struct {
-	Event string `json:"event_type"`
-	Sender string `json:"sender"`
-	BlockNumber uint64 `json:"block_number"`
-	Amount *big.Int `json:"amount"`
-	Timestamp time.Time `json:"timestamp"`
-}
-
-// MoneySentEvent struct
-type MoneySentEvent struct {
-	Event string `json:"event_type"`
-	Beneficiary string `json:"beneficiary"`
-	BlockNumber uint64 `json:"block_number"`
-	Amount *big.Int `json:"amount"`
-	Timestamp time.Time `json:"timestamp"`
-}
-
-// OwnershipTransferredEvent struct
-type OwnershipTransferredEvent struct {
-	Event string `json:"event_type"`
-	PreviousOwner string `json:"previous_owner"`
-	NewOwner string `json:"new_owner"`
-	BlockNumber uint64 `json:"block_number"`
-	Timestamp time.Time `json:"timestamp"`
+type monitor struct {
+	contractAddress string
+	options MonitorOptions
+	connection *connection
+	confirmer *confirmer
+	headers *headerCache
+	progress *progress
+	contract *contracts.ContractFilterer
+	// from next block to replay when there is no checkpoint
+	from *uint64
+	// emitted last position written to the sink, the checkpoint moves to it once the sink acknowledges it.
+	// Retractions move it back and invalidate the flushes in progress
+	emitted *Position
+	// highest position written to the sink, it is not moved back so every removed log of a reorg is retracted
+	highest *Position
+	retractions int
+	mutex sync.Mutex
 }
 
 // NewMonitor returns a new runner instance
-func NewMonitor(contractAddress string) Monitor {
-	return &monitor{
+func NewMonitor(contractAddress string, options MonitorOptions) Monitor {
+	if options.PageSize == 0 {
+		options.PageSize = DefaultPageSize
+	}
+	if options.Checkpoint == nil {
+		options.Checkpoint, _ = NewCheckpoint("")
+	}
+	m := &monitor{
 		contractAddress: contractAddress,
+		options: options,
+		headers: newHeaderCache(),
+		progress: newProgress(contractAddress),
+		from: options.FromBlock,
+	}
+	confirmations := options.Confirmations
+	if options.PollInterval > 0 {
+		// only the confirmed blocks are polled
+		confirmations = 0
 	}
+	m.confirmer = newConfirmer(confirmations, m.emit)
+	return m
 }
 
-// Start register to listen blockchain events
+// Start subscribes to the contract logs and dispatch them as events keeping the block and log index order
 func (m *monitor) Start(ctx context.Context, client *ethclient.Client) error {
-	log.Printf("start monitoring at %s\n", m.contractAddress)
+	log.Printf("start monitoring at %s\n", m.name())
 
 	err := validateContractAddress(ctx, client, m.contractAddress)
 	if err != nil {
 		return err
 	}
-	contract, err := contracts.NewContract(common.HexToAddress(m.contractAddress), client)
+	contract, err := contracts.NewContractFilterer(common.HexToAddress(m.contractAddress), client)
 	if err != nil {
 		return err
 	}
+	m.contract = contract
+	m.connection = newConnection(m.options.Endpoint, client)
 
 	eg := new(errgroup.Group)
 	eg.Go(func() error {
-		return m.watchAllowanceChanged(ctx, contract)
+		return m.keepWatching(ctx)
 	})
 	eg.Go(func() error {
-		return m.watchMoneySent(ctx, contract)
+		return m.confirmer.trackHead(ctx, m.connection)
 	})
 	eg.Go(func() error {
-		return m.watchMoneyReceived(ctx, contract)
+		return m.refreshMetrics(ctx)
 	})
 	eg.Go(func() error {
-		return m.watchOwnershipTransferred(ctx, contract)
+		return m.keepFlushing(ctx)
 	})
 	if err = eg.Wait(); err != nil {
 		return err
@@ -99,17 +130,48 @@ func (m *monitor) Start(ctx context.Context, client *ethclient.Client) error {
 	return nil
 }
 
-func (m *monitor) watchAllowanceChanged(ctx context.Context, contract *contracts.Contract) error {
-	events := make(chan *contracts.ContractAllowanceChanged)
-	opts := &bind.WatchOpts{
-		Start:   nil,
-		Context: ctx,
+// keepWatching watches or polls the contract logs, it starts again with a new connection every time they fail
+func (m *monitor) keepWatching(ctx context.Context) error {
+	watch := m.watch
+	if m.options.PollInterval > 0 {
+		watch = m.poll
 	}
-	subscription, err := contract.WatchAllowanceChanged(opts, events, nil, nil)
+	client, generation := m.connection.get()
+	for {
+		err := watch(ctx, client)
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+		if m.options.Endpoint == "" {
+			return err
+		}
+		log.Printf("%s subscription dropped: %s\n", m.name(), err.Error())
+		watchErr := err
+		client, generation, err = m.connection.reconnect(ctx, generation)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("%s subscription dropped (%s), unable to reconnect: %w", m.name(), watchErr, err)
+		}
+	}
+}
+
+// watch subscribes to the contract logs, replays the history and processes the live logs until the context is done
+// or the subscription fails. The subscription starts before the replay so there is no gap between them
+func (m *monitor) watch(ctx context.Context, client *ethclient.Client) error {
+	logs := make(chan types.Log, eventsBuffer)
+	subscription, err := client.SubscribeFilterLogs(ctx, m.query(), logs)
 	if err != nil {
 		return err
 	}
 	defer subscription.Unsubscribe()
+	defer m.progress.subscribed(nil)
+
+	if err = m.backfill(ctx, client); err != nil {
+		return err
+	}
+	m.progress.subscribed(logs)
 
 	for {
 		select {
@@ -117,125 +179,287 @@ func (m *monitor) watchAllowanceChanged(ctx context.Context, contract *contracts
 			return nil
 		case errChan := <-subscription.Err():
 			return errChan
-		case event := <-events:
-			j, _ := json.MarshalIndent(
-				AllowanceChangedEvent{
-					Event:       "AllowanceChanged",
-					Sender:      event.Sender.Hex(),
-					Beneficiary: event.Beneficiary.Hex(),
-					PrevAmount:  weiToEther(event.PrevAmount),
-					NewAmount:   weiToEther(event.NewAmount),
-					Timestamp: time.Now(),
-				},
-				"",
-				"  ",
-			)
-			fmt.Println(string(j))
+		case raw := <-logs:
+			if err = m.process(ctx, client, raw); err != nil {
+				return err
+			}
 		}
 	}
 }
 
-func (m *monitor) watchMoneySent(ctx context.Context, contract *contracts.Contract) error {
-	events := make(chan *contracts.ContractMoneySent)
-	opts := &bind.WatchOpts{
-		Start:   nil,
-		Context: ctx,
-	}
-	subscription, err := contract.WatchMoneySent(opts, events, nil)
-	if err != nil {
+// poll replays the history and then requests the new logs of the confirmed blocks on every interval
+func (m *monitor) poll(ctx context.Context, client *ethclient.Client) error {
+	if err := m.backfill(ctx, client); err != nil {
 		return err
 	}
-	defer subscription.Unsubscribe()
-
+	ticker := time.NewTicker(m.options.PollInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case errChan := <-subscription.Err():
-			return errChan
-		case event := <-events:
-			j, _ := json.MarshalIndent(
-				MoneySentEvent{
-					Event:       "MoneySent",
-					Beneficiary: event.Beneficiary.Hex(),
-					BlockNumber: event.Raw.BlockNumber,
-					Amount:      weiToEther(event.Amount),
-					Timestamp: time.Now(),
-				},
-				"",
-				"  ",
-			)
-			fmt.Println(string(j))
+		case <-ticker.C:
+			if err := m.backfill(ctx, client); err != nil {
+				return err
+			}
 		}
 	}
 }
 
-func (m *monitor) watchMoneyReceived(ctx context.Context, contract *contracts.Contract) error {
-	events := make(chan *contracts.ContractMoneyReceived)
-	opts := &bind.WatchOpts{
-		Start:   nil,
-		Context: ctx,
+// backfill replay the logs from the last processed position, or the configured block, up to the current head in pages.
+// The next replay starts after the current head so the blocks missed while reconnecting are not lost
+func (m *monitor) backfill(ctx context.Context, client *ethclient.Client) error {
+	from := m.from
+	if last, ok := m.lastPosition(); ok && (from == nil || last.Block > *from) {
+		from = &last.Block
+		log.Printf("resuming %s from block %d\n", m.name(), last.Block)
 	}
-	subscription, err := contract.WatchMoneyReceived(opts, events, nil)
+	head, err := m.head(ctx, client)
 	if err != nil {
 		return err
 	}
-	defer subscription.Unsubscribe()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case errChan := <-subscription.Err():
-			return errChan
-		case event := <-events:
-			j, _ := json.MarshalIndent(
-				MoneyReceivedEvent{
-					Event:       "MoneyReceived",
-					Sender:      event.From.Hex(),
-					BlockNumber: event.Raw.BlockNumber,
-					Amount:      weiToEther(event.Amount),
-					Timestamp: time.Now(),
-				},
-				"",
-				"  ",
-			)
-			fmt.Println(string(j))
+	if from != nil {
+		query := m.query()
+		for start := *from; start <= head; start += m.options.PageSize {
+			end := start + m.options.PageSize - 1
+			if end > head {
+				end = head
+			}
+			query.FromBlock = new(big.Int).SetUint64(start)
+			query.ToBlock = new(big.Int).SetUint64(end)
+			logs, err := client.FilterLogs(ctx, query)
+			if err != nil {
+				return err
+			}
+			for _, raw := range logs {
+				if err = m.process(ctx, client, raw); err != nil {
+					return err
+				}
+			}
+			if err = m.flush(ctx); err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+				log.Printf("unable to flush %s events: %s\n", m.name(), err.Error())
+			}
+			m.progress.processed(end)
 		}
 	}
+	if m.from == nil || head+1 > *m.from {
+		next := head + 1
+		m.from = &next
+	}
+	return nil
+}
+
+// head returns the last block to replay, when polling it is the last confirmed block since the removed logs
+// are not notified. A head behind the already replayed blocks, i.e. a node still syncing, replays nothing
+func (m *monitor) head(ctx context.Context, client *ethclient.Client) (uint64, error) {
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if m.options.PollInterval == 0 {
+		return head, nil
+	}
+	if head < m.options.Confirmations {
+		return 0, nil
+	}
+	return head - m.options.Confirmations, nil
+}
+
+// query returns the filter of the contract logs with the watched event topics
+func (m *monitor) query() ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		Addresses: []common.Address{common.HexToAddress(m.contractAddress)},
+		Topics:    m.options.Filter.topics(),
+	}
 }
 
-func (m *monitor) watchOwnershipTransferred(ctx context.Context, contract *contracts.Contract) error {
-	events := make(chan *contracts.ContractOwnershipTransferred)
-	opts := &bind.WatchOpts{
-		Start:   nil,
-		Context: ctx,
+// process decodes the log and queues the event unless it was already processed, removed events are retracted.
+// The event location is set from the raw log and its block header
+func (m *monitor) process(ctx context.Context, client *ethclient.Client, raw types.Log) error {
+	position := Position{Block: raw.BlockNumber, LogIndex: raw.Index}
+	last, ok := m.lastEmitted()
+	if !raw.Removed && ok && !position.After(last) {
+		return nil
 	}
-	subscription, err := contract.WatchOwnershipTransferred(opts, events, nil, nil)
+	event, err := decodeEvent(m.contract, raw)
 	if err != nil {
 		return err
 	}
-	defer subscription.Unsubscribe()
+	if event == nil || !m.options.Filter.accepts(event) {
+		return nil
+	}
+	blockTime, err := m.headers.blockTime(ctx, client, raw.BlockHash)
+	if err != nil {
+		return err
+	}
+	eventLog := newEventLog(raw, blockTime)
+	eventLog.Label = m.options.Label
+	event.setLog(eventLog)
+	if raw.Removed {
+		m.retract(ctx, raw, event)
+		return nil
+	}
+	m.confirmer.add(ctx, m.stream(), raw, event)
+	return nil
+}
+
+// emit sends the event to the sink and moves the checkpoint, the checkpoint waits for the flush when the sink
+// acknowledges the events
+func (m *monitor) emit(ctx context.Context, stream string, raw types.Log, event Event) {
+	m.write(ctx, event)
+	m.progress.emitted(event)
+	m.progress.processed(raw.BlockNumber)
+	position := Position{Block: raw.BlockNumber, LogIndex: raw.Index}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.emitted = &position
+	if m.highest == nil || position.After(*m.highest) {
+		m.highest = &position
+	}
+	if _, ok := m.options.Sink.(EventFlusher); !ok {
+		m.options.Checkpoint.Set(stream, position)
+	}
+}
+
+// retract drops a removed event still waiting for confirmations or emits a retracted event if it was already emitted.
+// The checkpoint is moved before the oldest removed block so the events of the new chain are processed
+func (m *monitor) retract(ctx context.Context, raw types.Log, event Event) {
+	if m.confirmer.drop(m.stream(), raw) {
+		return
+	}
+	position := Position{Block: raw.BlockNumber, LogIndex: raw.Index}
+	last, ok := m.highestEmitted()
+	if !ok || position.After(last) {
+		return
+	}
+	m.write(ctx, &RetractedEvent{
+		Event:     Retracted,
+		Retracted: event,
+		EventLog:  event.Log(),
+	})
+	if raw.BlockNumber > 0 {
+		before := Position{Block: raw.BlockNumber - 1, LogIndex: math.MaxUint32}
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		m.retractions++
+		if m.emitted == nil || m.emitted.After(before) {
+			m.emitted = &before
+		}
+		if checkpoint, ok := m.options.Checkpoint.Get(m.stream()); !ok || checkpoint.After(before) {
+			m.options.Checkpoint.Set(m.stream(), before)
+		}
+	}
+}
 
+// highestEmitted returns the highest position written to the sink, the checkpoint if nothing was written yet.
+// The checkpoint is kept as the highest position since the retractions move it back
+func (m *monitor) highestEmitted() (Position, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.highest == nil {
+		checkpoint, ok := m.options.Checkpoint.Get(m.stream())
+		if !ok {
+			return Position{}, false
+		}
+		m.highest = &checkpoint
+	}
+	return *m.highest, true
+}
+
+// lastEmitted returns the last position written to the sink, the checkpoint if nothing was written yet
+func (m *monitor) lastEmitted() (Position, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.emitted != nil {
+		return *m.emitted, true
+	}
+	return m.options.Checkpoint.Get(m.stream())
+}
+
+// flush waits for the sink to acknowledge the emitted events and moves the checkpoint to them. The checkpoint is
+// not moved if an event was retracted meanwhile
+func (m *monitor) flush(ctx context.Context) error {
+	flusher, ok := m.options.Sink.(EventFlusher)
+	if !ok {
+		return nil
+	}
+	m.mutex.Lock()
+	emitted, retractions := m.emitted, m.retractions
+	m.mutex.Unlock()
+	if emitted == nil {
+		return nil
+	}
+	if err := flusher.Flush(ctx); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	last, ok := m.options.Checkpoint.Get(m.stream())
+	if retractions == m.retractions && (!ok || emitted.After(last)) {
+		m.options.Checkpoint.Set(m.stream(), *emitted)
+	}
+	return nil
+}
+
+// keepFlushing moves the checkpoint to the acknowledged live events on every checkpoint interval
+func (m *monitor) keepFlushing(ctx context.Context) error {
+	ticker := time.NewTicker(checkpointFlushInterval)
+	defer ticker.Stop()
+	// the same error is logged once, a durable sink that dropped an event fails every flush until the next start
+	failure := ""
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
-		case errChan := <-subscription.Err():
-			return errChan
-		case event := <-events:
-			j, _ := json.MarshalIndent(
-				OwnershipTransferredEvent{
-					Event:         "OwnershipTransferred",
-					PreviousOwner: event.PreviousOwner.Hex(),
-					NewOwner: event.NewOwner.Hex(),
-					BlockNumber:   event.Raw.BlockNumber,
-					Timestamp: time.Now(),
-				},
-				"",
-				"  ",
-			)
-			fmt.Println(string(j))
+		case <-ticker.C:
+			err := m.flush(ctx)
+			if err != nil && ctx.Err() == nil && err.Error() != failure {
+				log.Printf("unable to flush %s events: %s\n", m.name(), err.Error())
+			}
+			failure = ""
+			if err != nil {
+				failure = err.Error()
+			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+// write sends the event to the sink logging the errors
+func (m *monitor) write(ctx context.Context, event Event) {
+	if err := m.options.Sink.Write(ctx, event); err != nil {
+		log.Printf("unable to write %s event: %s\n", event.Type(), err.Error())
+	}
+}
+
+// name returns the contract address with its label for the logs
+func (m *monitor) name() string {
+	if m.options.Label == "" {
+		return m.contractAddress
+	}
+	return m.options.Label + " (" + m.contractAddress + ")"
+}
+
+// stream returns the checkpoint key of the contract
+func (m *monitor) stream() string {
+	return strings.ToLower(m.contractAddress)
+}
+
+// lastPosition returns the contract checkpoint. Checkpoints saved with a position per event type
+// resume from the oldest of them
+func (m *monitor) lastPosition() (Position, bool) {
+	if last, ok := m.options.Checkpoint.Get(m.stream()); ok {
+		return last, true
+	}
+	var oldest Position
+	found := false
+	for _, eventType := range eventTypes {
+		last, ok := m.options.Checkpoint.Get(m.stream() + ":" + eventType)
+		if ok && (!found || oldest.After(last)) {
+			oldest, found = last, true
+		}
+	}
+	return oldest, found
+}
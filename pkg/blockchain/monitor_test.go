@@ -0,0 +1,85 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/core/types"
+	"math"
+	"testing"
+)
+
+const monitoredContract = "0x00000000000000000000000000000000000000cc"
+
+// recordingSink keeps the written events
+type recordingSink struct {
+	events []Event
+}
+
+// Write records the event
+func (s *recordingSink) Write(_ context.Context, event Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+// sentLog returns the raw log of a MoneySent event and the event
+func sentLog(block uint64, index uint, removed bool) (types.Log, Event) {
+	raw := types.Log{BlockNumber: block, Index: index, Removed: removed}
+	event := &MoneySentEvent{Event: MoneySent, Beneficiary: alice, EventLog: EventLog{BlockNumber: block, LogIndex: index}}
+	return raw, event
+}
+
+// retracted returns the positions of the retracted events written to the sink
+func retracted(events []Event) []Position {
+	var positions []Position
+	for _, event := range events {
+		if r, ok := event.(*RetractedEvent); ok {
+			positions = append(positions, Position{Block: r.BlockNumber, LogIndex: r.LogIndex})
+		}
+	}
+	return positions
+}
+
+func TestMonitorRetractReorg(t *testing.T) {
+	sink := &recordingSink{}
+	m := NewMonitor(monitoredContract, MonitorOptions{Sink: sink}).(*monitor)
+	ctx := context.Background()
+	for _, position := range []Position{{10, 0}, {11, 0}, {12, 1}} {
+		raw, event := sentLog(position.Block, position.LogIndex, false)
+		m.emit(ctx, m.stream(), raw, event)
+	}
+	// the removed logs of a reorg come oldest first, a log that was not emitted is not retracted
+	for _, position := range []Position{{11, 0}, {12, 1}, {13, 0}} {
+		raw, event := sentLog(position.Block, position.LogIndex, true)
+		m.retract(ctx, raw, event)
+	}
+
+	got := retracted(sink.events)
+	if len(got) != 2 || got[0] != (Position{11, 0}) || got[1] != (Position{12, 1}) {
+		t.Fatalf("retracted = %v, want blocks 11 and 12", got)
+	}
+	before := Position{Block: 10, LogIndex: math.MaxUint32}
+	if last, ok := m.lastEmitted(); !ok || last != before {
+		t.Errorf("last emitted = %v, want %v", last, before)
+	}
+	if checkpoint, ok := m.options.Checkpoint.Get(m.stream()); !ok || checkpoint != before {
+		t.Errorf("checkpoint = %v, want %v", checkpoint, before)
+	}
+}
+
+func TestMonitorRetractAfterRestart(t *testing.T) {
+	checkpoint, _ := NewCheckpoint("")
+	checkpoint.Set(monitoredContract, Position{Block: 12, LogIndex: 1})
+	sink := &recordingSink{}
+	m := NewMonitor(monitoredContract, MonitorOptions{Sink: sink, Checkpoint: checkpoint}).(*monitor)
+	ctx := context.Background()
+	for _, position := range []Position{{11, 0}, {12, 1}} {
+		raw, event := sentLog(position.Block, position.LogIndex, true)
+		m.retract(ctx, raw, event)
+	}
+
+	if got := retracted(sink.events); len(got) != 2 {
+		t.Fatalf("retracted = %v, want blocks 11 and 12", got)
+	}
+	if got, _ := checkpoint.Get(monitoredContract); got != (Position{Block: 10, LogIndex: math.MaxUint32}) {
+		t.Errorf("checkpoint = %v, want the end of block 10", got)
+	}
+}
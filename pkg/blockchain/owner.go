@@ -2,16 +2,14 @@ package blockchain
 
 import (
 	"context"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 // Owner interface
 type Owner interface {
-	GetOwner(ctx context.Context, client *ethclient.Client) (string, error)
-	TransferOwner(ctx context.Context, client *ethclient.Client, targetAddress string) error
+	GetOwner(ctx context.Context, client *ethclient.Client, block BlockRef) (string, error)
+	TransferOwner(ctx context.Context, client *ethclient.Client, targetAddress string) (TransactionInfo, error)
 }
 
 type owner struct {
@@ -27,39 +25,31 @@ func NewOwnerRunner(privateKey string, contractAddress string) Owner {
 	}
 }
 
-// GetOwner returns the contract owner address
-func (o *owner) GetOwner(ctx context.Context, client *ethclient.Client) (string, error) {
+// GetOwner returns the contract owner address at a block
+func (o *owner) GetOwner(ctx context.Context, client *ethclient.Client, block BlockRef) (string, error) {
 	contract, err := getContract(ctx, client, o.contractAddress)
 	if err != nil {
 		return "", err
 	}
 
-	ownerAddress, err := contract.Owner(&bind.CallOpts{Pending: false, Context: ctx})
+	ownerAddress, err := contract.Owner(block.callOpts(ctx))
 	if err != nil {
-		return "", err
+		return "", stateError(err)
 	}
 	return ownerAddress.Hex(), nil
 }
 
 // TransferOwner transfer the ownership to a target address
-func (o *owner) TransferOwner(ctx context.Context, client *ethclient.Client, targetAddress string) error {
+func (o *owner) TransferOwner(ctx context.Context, client *ethclient.Client, targetAddress string) (TransactionInfo, error) {
 	contract, err := getContract(ctx, client, o.contractAddress)
 	if err != nil {
-		return err
+		return TransactionInfo{}, err
 	}
 	signer, err := getSigner(ctx, client)
 	if err != nil {
-		return err
+		return TransactionInfo{}, err
 	}
 
 	tx, txErr := contract.TransferOwnership(signer, common.HexToAddress(targetAddress))
-	if txErr != nil {
-		return txErr
-	}
-	receipt, err := bind.WaitMined(ctx, client, tx)
-	if receipt.Status != types.ReceiptStatusSuccessful || err != nil {
-		return err
-	}
-	processTransaction(ctx, tx, "transfer owner")
-	return nil
+	return waitTransaction(ctx, client, tx, txErr, "transfer owner")
 }
\ No newline at end of file
@@ -0,0 +1,136 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+)
+
+const (
+	BalanceDrift = "balance"
+	AllowanceDrift = "allowance"
+	AllowanceGap = "allowance_gap"
+)
+
+// Drift struct, a difference between the state derived from the events and the contract state. The allowance gaps
+// are AllowanceChanged events whose previous amount is not the allowance derived from the former events, the block
+// and transaction locate the event
+type Drift struct {
+	Kind string `json:"kind"`
+	Subject string `json:"subject"`
+	Expected *big.Int `json:"expected"`
+	Actual *big.Int `json:"actual"`
+	Block uint64 `json:"block,omitempty"`
+	TxHash string `json:"tx_hash,omitempty"`
+}
+
+// AllowanceCheck struct, the allowance of a beneficiary derived from the events and read from the contract
+type AllowanceCheck struct {
+	Beneficiary string `json:"beneficiary"`
+	Expected *big.Int `json:"expected"`
+	Actual *big.Int `json:"actual"`
+}
+
+// Reconciliation struct, the state derived from the events compared with the contract state at the same block.
+// The amounts are in wei
+type Reconciliation struct {
+	Contract string `json:"contract"`
+	Block uint64 `json:"block"`
+	Events int `json:"events"`
+	ExpectedBalance *big.Int `json:"expected_balance"`
+	ActualBalance *big.Int `json:"actual_balance"`
+	Allowances []AllowanceCheck `json:"allowances"`
+	Drifts []Drift `json:"drifts"`
+}
+
+// Drifted reports whether the events and the contract state differ
+func (r Reconciliation) Drifted() bool {
+	return len(r.Drifts) > 0
+}
+
+// Reconcile replays the contract events up to a block and compares the expected balance, received minus sent money, and
+// the expected allowances with the contract state at that block
+func Reconcile(ctx context.Context, client *ethclient.Client, contractAddress string, at BlockRef) (Reconciliation, error) {
+	number, err := at.Resolve(ctx, client)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	block := number.Uint64()
+	history, err := NewHistory(client, contractAddress)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	records, err := history.Records(ctx, HistoryQuery{
+		Types: []string{AllowanceChanged, MoneySent, MoneyReceived},
+		ToBlock: &block,
+	})
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	state, err := NewContractState(client, contractAddress)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	state = state.At(number)
+
+	r, expected, beneficiaries := expectedState(contractAddress, block, records)
+	balance, err := state.Balance(ctx)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	actual, err := state.Allowances(ctx, beneficiaries)
+	if err != nil {
+		return Reconciliation{}, err
+	}
+	r.compare(balance, expected, beneficiaries, actual)
+	return r, nil
+}
+
+// expectedState replays the records into the expected balance and allowances, the allowance gaps are added as drifts.
+// The beneficiaries are returned in the order of their first event
+func expectedState(contractAddress string, block uint64, records []Record) (Reconciliation, map[string]*big.Int, []string) {
+	r := Reconciliation{Contract: contractAddress, Block: block, Events: len(records), ExpectedBalance: new(big.Int)}
+	expected := map[string]*big.Int{}
+	var beneficiaries []string
+	for _, record := range records {
+		switch record.Type {
+		case MoneyReceived:
+			r.ExpectedBalance.Add(r.ExpectedBalance, record.Amount)
+		case MoneySent:
+			r.ExpectedBalance.Sub(r.ExpectedBalance, record.Amount)
+		case AllowanceChanged:
+			previous, ok := expected[record.Beneficiary]
+			if !ok {
+				previous = new(big.Int)
+				beneficiaries = append(beneficiaries, record.Beneficiary)
+			}
+			if previous.Cmp(record.PrevAmount) != 0 {
+				r.Drifts = append(r.Drifts, Drift{
+					Kind: AllowanceGap,
+					Subject: record.Beneficiary,
+					Expected: previous,
+					Actual: record.PrevAmount,
+					Block: record.BlockNumber,
+					TxHash: record.TxHash,
+				})
+			}
+			expected[record.Beneficiary] = record.Amount
+		}
+	}
+	return r, expected, beneficiaries
+}
+
+// compare adds the contract balance and allowances and their drifts from the expected state
+func (r *Reconciliation) compare(balance *big.Int, expected map[string]*big.Int, beneficiaries []string, actual map[string]*big.Int) {
+	r.ActualBalance = balance
+	if r.ActualBalance.Cmp(r.ExpectedBalance) != 0 {
+		r.Drifts = append(r.Drifts, Drift{Kind: BalanceDrift, Subject: r.Contract, Expected: r.ExpectedBalance, Actual: r.ActualBalance})
+	}
+	for _, beneficiary := range beneficiaries {
+		check := AllowanceCheck{Beneficiary: beneficiary, Expected: expected[beneficiary], Actual: actual[beneficiary]}
+		r.Allowances = append(r.Allowances, check)
+		if check.Actual.Cmp(check.Expected) != 0 {
+			r.Drifts = append(r.Drifts, Drift{Kind: AllowanceDrift, Subject: beneficiary, Expected: check.Expected, Actual: check.Actual})
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestExpectedState(t *testing.T) {
+	type gap struct {
+		subject  string
+		expected string
+		actual   string
+		block    uint64
+	}
+	tests := []struct {
+		name       string
+		records    []Record
+		balance    string
+		allowances map[string]string
+		gaps       []gap
+	}{
+		{
+			name: "consistent history",
+			records: []Record{
+				receivedRecord(t, "0x1", 1, ownerAddress, "10"),
+				allowanceRecord(t, "0x2", 2, alice, "0", "4"),
+				allowanceRecord(t, "0x3", 3, alice, "4", "1"),
+				sentRecord(t, "0x3", 3, alice, "3"),
+			},
+			balance:    "7",
+			allowances: map[string]string{alice: "1"},
+		},
+		{
+			name: "missing allowance change",
+			records: []Record{
+				allowanceRecord(t, "0x1", 1, alice, "0", "4"),
+				allowanceRecord(t, "0x3", 3, alice, "2", "5"),
+			},
+			balance:    "0",
+			allowances: map[string]string{alice: "5"},
+			gaps:       []gap{{alice, "4", "2", 3}},
+		},
+		{
+			name: "first event of a beneficiary with an allowance",
+			records: []Record{
+				allowanceRecord(t, "0x1", 1, alice, "0", "1"),
+				allowanceRecord(t, "0x2", 2, bob, "3", "2"),
+				allowanceRecord(t, "0x3", 3, bob, "2", "0"),
+				allowanceRecord(t, "0x4", 4, bob, "1", "6"),
+			},
+			balance:    "0",
+			allowances: map[string]string{alice: "1", bob: "6"},
+			gaps:       []gap{{bob, "0", "3", 2}, {bob, "0", "1", 4}},
+		},
+		{
+			name: "money sent above the balance",
+			records: []Record{
+				receivedRecord(t, "0x1", 1, bob, "1"),
+				sentRecord(t, "0x2", 2, alice, "3"),
+			},
+			balance:    "-2",
+			allowances: map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, expected, beneficiaries := expectedState(ownerAddress, 9, test.records)
+			if r.Contract != ownerAddress || r.Block != 9 || r.Events != len(test.records) {
+				t.Errorf("reconciliation = %s at %d with %d events", r.Contract, r.Block, r.Events)
+			}
+			if want := signedWei(t, test.balance); r.ExpectedBalance.Cmp(want) != 0 {
+				t.Errorf("expected balance = %s, want %s", r.ExpectedBalance, want)
+			}
+			if len(beneficiaries) != len(test.allowances) {
+				t.Errorf("beneficiaries = %v, want %d", beneficiaries, len(test.allowances))
+			}
+			for beneficiary, allowance := range test.allowances {
+				if got := expected[beneficiary]; got == nil || got.Cmp(wei(t, allowance)) != 0 {
+					t.Errorf("expected allowance of %s = %v, want %s", beneficiary, got, allowance)
+				}
+			}
+			if len(r.Drifts) != len(test.gaps) {
+				t.Fatalf("got %d drifts, want %d: %+v", len(r.Drifts), len(test.gaps), r.Drifts)
+			}
+			for i, want := range test.gaps {
+				got := r.Drifts[i]
+				if got.Kind != AllowanceGap || got.Subject != want.subject || got.Block != want.block ||
+					got.Expected.Cmp(wei(t, want.expected)) != 0 || got.Actual.Cmp(wei(t, want.actual)) != 0 {
+					t.Errorf("drift %d = %+v, want a gap of %s at block %d from %s to %s", i, got, want.subject,
+						want.block, want.expected, want.actual)
+				}
+			}
+		})
+	}
+}
+
+func TestReconciliationCompare(t *testing.T) {
+	records := []Record{
+		receivedRecord(t, "0x1", 1, ownerAddress, "10"),
+		allowanceRecord(t, "0x2", 2, alice, "0", "4"),
+		allowanceRecord(t, "0x3", 3, bob, "0", "2"),
+	}
+	tests := []struct {
+		name    string
+		balance string
+		actual  map[string]string
+		drifts  []string
+	}{
+		{"in sync", "10", map[string]string{alice: "4", bob: "2"}, nil},
+		{"balance drift", "9", map[string]string{alice: "4", bob: "2"}, []string{BalanceDrift}},
+		{"allowance drift", "10", map[string]string{alice: "4", bob: "0"}, []string{AllowanceDrift}},
+		{"both", "12", map[string]string{alice: "1", bob: "2"}, []string{BalanceDrift, AllowanceDrift}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, expected, beneficiaries := expectedState(ownerAddress, 3, records)
+			actual := map[string]*big.Int{}
+			for beneficiary, allowance := range test.actual {
+				actual[beneficiary] = wei(t, allowance)
+			}
+			r.compare(wei(t, test.balance), expected, beneficiaries, actual)
+			if len(r.Allowances) != 2 || r.Allowances[0].Beneficiary != alice || r.Allowances[1].Beneficiary != bob {
+				t.Errorf("allowances = %+v, want alice and bob in event order", r.Allowances)
+			}
+			if r.Drifted() != (len(test.drifts) > 0) {
+				t.Errorf("Drifted = %t with drifts %+v", r.Drifted(), r.Drifts)
+			}
+			if len(r.Drifts) != len(test.drifts) {
+				t.Fatalf("drifts = %+v, want %v", r.Drifts, test.drifts)
+			}
+			for i, kind := range test.drifts {
+				if r.Drifts[i].Kind != kind {
+					t.Errorf("drift %d = %s, want %s", i, r.Drifts[i].Kind, kind)
+				}
+			}
+		})
+	}
+}
+
+// signedWei parses an ether amount that may be negative
+func signedWei(t *testing.T, ether string) *big.Int {
+	t.Helper()
+	if len(ether) > 0 && ether[0] == '-' {
+		return new(big.Int).Neg(wei(t, ether[1:]))
+	}
+	return wei(t, ether)
+}
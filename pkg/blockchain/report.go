@@ -0,0 +1,239 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/pkg/price"
+	"github.com/ethereum/go-ethereum/common"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const (
+	DailyPeriod     = "daily"
+	WeeklyPeriod    = "weekly"
+	MonthlyPeriod   = "monthly"
+	QuarterlyPeriod = "quarterly"
+	YearlyPeriod    = "yearly"
+)
+
+var ErrInvalidPeriod = errors.New("period should be daily, weekly, monthly, quarterly or yearly")
+
+// ReportParty struct, the amounts in wei of a beneficiary or a funder in a period
+type ReportParty struct {
+	Address          string   `json:"address"`
+	Inflows          *big.Int `json:"inflows,omitempty"`
+	Outflows         *big.Int `json:"outflows,omitempty"`
+	AllowanceGranted *big.Int `json:"allowance_granted,omitempty"`
+}
+
+// ReportPeriod struct, the contract movements in wei of a period. The balances are derived from the events
+type ReportPeriod struct {
+	Period           string        `json:"period"`
+	Start            time.Time     `json:"start"`
+	End              time.Time     `json:"end"`
+	OpeningBalance   *big.Int      `json:"opening_balance"`
+	Inflows          *big.Int      `json:"inflows"`
+	Outflows         *big.Int      `json:"outflows"`
+	NetChange        *big.Int      `json:"net_change"`
+	ClosingBalance   *big.Int      `json:"closing_balance"`
+	AllowanceGranted *big.Int      `json:"allowance_granted"`
+	AllowanceSpent   *big.Int      `json:"allowance_spent"`
+	Beneficiaries    []ReportParty `json:"beneficiaries"`
+	Funders          []ReportParty `json:"funders"`
+	Fiat             *ReportValues `json:"fiat,omitempty"`
+}
+
+// ReportValues struct, the fiat values of a period. The flows are valued at the price of their events and the balances
+// at the price of the period start and end, the revaluation is the change of the balance value not explained by the flows
+type ReportValues struct {
+	OpeningBalance *price.Value `json:"opening_balance"`
+	Inflows        *price.Value `json:"inflows"`
+	Outflows       *price.Value `json:"outflows"`
+	NetChange      *price.Value `json:"net_change"`
+	ClosingBalance *price.Value `json:"closing_balance"`
+	Revaluation    *price.Value `json:"revaluation"`
+}
+
+// NewReport aggregates the records by period from the period of the from time to the period of the to time. The records
+// should start at the contract creation so the opening balance of the first period is right. The periods are valued
+// when there is a price table
+func NewReport(records []Record, period string, from time.Time, to time.Time, prices price.Table) ([]ReportPeriod, error) {
+	start, err := PeriodStart(period, from)
+	if err != nil {
+		return nil, err
+	}
+	balance := new(big.Int)
+	var report []ReportPeriod
+	next := 0
+	for !start.After(to) {
+		end := nextPeriod(period, start)
+		current := ReportPeriod{
+			Period:           periodName(period, start),
+			Start:            start,
+			End:              end,
+			Inflows:          new(big.Int),
+			Outflows:         new(big.Int),
+			AllowanceGranted: new(big.Int),
+			AllowanceSpent:   new(big.Int),
+		}
+		values := &ReportValues{Inflows: price.NewValue(), Outflows: price.NewValue()}
+		beneficiaries := make(map[common.Address]*ReportParty)
+		funders := make(map[common.Address]*ReportParty)
+		for ; next < len(records) && records[next].Timestamp.Before(end); next++ {
+			record := records[next]
+			inPeriod := !record.Timestamp.Before(start)
+			if inPeriod && prices != nil && (record.Type == MoneyReceived || record.Type == MoneySent) {
+				value, err := prices.Value(record.Amount, record.Timestamp)
+				if err != nil {
+					return nil, err
+				}
+				if record.Type == MoneyReceived {
+					values.Inflows.Add(value)
+				} else {
+					values.Outflows.Add(value)
+				}
+			}
+			switch record.Type {
+			case MoneyReceived:
+				balance.Add(balance, record.Amount)
+				if inPeriod {
+					current.Inflows.Add(current.Inflows, record.Amount)
+					funder := party(funders, record.Sender, true)
+					funder.Inflows.Add(funder.Inflows, record.Amount)
+				}
+			case MoneySent:
+				balance.Sub(balance, record.Amount)
+				if inPeriod {
+					current.Outflows.Add(current.Outflows, record.Amount)
+					current.AllowanceSpent.Add(current.AllowanceSpent, record.Amount)
+					beneficiary := party(beneficiaries, record.Beneficiary, false)
+					beneficiary.Outflows.Add(beneficiary.Outflows, record.Amount)
+				}
+			case AllowanceChanged:
+				if change := new(big.Int).Sub(record.Amount, record.PrevAmount); inPeriod && change.Sign() > 0 {
+					current.AllowanceGranted.Add(current.AllowanceGranted, change)
+					beneficiary := party(beneficiaries, record.Beneficiary, false)
+					beneficiary.AllowanceGranted.Add(beneficiary.AllowanceGranted, change)
+				}
+			}
+		}
+		current.ClosingBalance = new(big.Int).Set(balance)
+		current.NetChange = new(big.Int).Sub(current.Inflows, current.Outflows)
+		current.OpeningBalance = new(big.Int).Sub(current.ClosingBalance, current.NetChange)
+		current.Beneficiaries = sortedParties(beneficiaries)
+		current.Funders = sortedParties(funders)
+		if prices != nil {
+			if current.Fiat, err = valuePeriod(current, values, prices); err != nil {
+				return nil, err
+			}
+		}
+		report = append(report, current)
+		start = end
+	}
+	return report, nil
+}
+
+// valuePeriod completes the fiat values of a period with its flows valued, the balances are valued at the last instant
+// before the period start and end
+func valuePeriod(p ReportPeriod, values *ReportValues, prices price.Table) (*ReportValues, error) {
+	var err error
+	if values.OpeningBalance, err = balanceValue(prices, p.OpeningBalance, p.Start.Add(-time.Nanosecond)); err != nil {
+		return nil, err
+	}
+	if values.ClosingBalance, err = balanceValue(prices, p.ClosingBalance, p.End.Add(-time.Nanosecond)); err != nil {
+		return nil, err
+	}
+	values.NetChange = price.NewValue().Add(values.Inflows).Sub(values.Outflows)
+	values.Revaluation = price.NewValue().Add(values.ClosingBalance).Sub(values.OpeningBalance).Sub(values.NetChange)
+	return values, nil
+}
+
+// balanceValue returns the value of a balance, an empty balance does not need a price
+func balanceValue(prices price.Table, balance *big.Int, t time.Time) (*price.Value, error) {
+	if balance.Sign() == 0 {
+		return price.NewValue(), nil
+	}
+	return prices.Value(balance, t)
+}
+
+// PeriodStart returns the start of the period holding the given time, in UTC
+func PeriodStart(period string, t time.Time) (time.Time, error) {
+	t = t.UTC()
+	switch period {
+	case DailyPeriod:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	case WeeklyPeriod:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		// the weeks start on Monday
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil
+	case MonthlyPeriod:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	case QuarterlyPeriod:
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC), nil
+	case YearlyPeriod:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, ErrInvalidPeriod
+}
+
+// nextPeriod returns the start of the next period
+func nextPeriod(period string, start time.Time) time.Time {
+	switch period {
+	case DailyPeriod:
+		return start.AddDate(0, 0, 1)
+	case WeeklyPeriod:
+		return start.AddDate(0, 0, 7)
+	case QuarterlyPeriod:
+		return start.AddDate(0, 3, 0)
+	case YearlyPeriod:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// periodName returns the period name, i.e. 2026-01 for a month or 2026-Q1 for a quarter
+func periodName(period string, start time.Time) string {
+	switch period {
+	case DailyPeriod:
+		return start.Format("2006-01-02")
+	case WeeklyPeriod:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case QuarterlyPeriod:
+		return fmt.Sprintf("%d-Q%d", start.Year(), (start.Month()-1)/3+1)
+	case YearlyPeriod:
+		return start.Format("2006")
+	}
+	return start.Format("2006-01")
+}
+
+// party returns the party of an address, creating it if it is not in the map. The funders only have inflows
+func party(parties map[common.Address]*ReportParty, address string, funder bool) *ReportParty {
+	key := common.HexToAddress(address)
+	if p, ok := parties[key]; ok {
+		return p
+	}
+	p := &ReportParty{Address: key.Hex()}
+	if funder {
+		p.Inflows = new(big.Int)
+	} else {
+		p.Outflows = new(big.Int)
+		p.AllowanceGranted = new(big.Int)
+	}
+	parties[key] = p
+	return p
+}
+
+// sortedParties returns the parties sorted by address
+func sortedParties(parties map[common.Address]*ReportParty) []ReportParty {
+	result := make([]ReportParty, 0, len(parties))
+	for _, p := range parties {
+		result = append(result, *p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
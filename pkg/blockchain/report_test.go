@@ -0,0 +1,214 @@
+package blockchain
+
+import (
+	"github.com/StevenRojas/sharedWallet/pkg/price"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeriodStart(t *testing.T) {
+	// 2026-05-14 is a Thursday
+	at := time.Date(2026, 5, 14, 15, 30, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	tests := []struct {
+		period string
+		start  string
+		name   string
+	}{
+		{DailyPeriod, "2026-05-14", "2026-05-14"},
+		{WeeklyPeriod, "2026-05-11", "2026-W20"},
+		{MonthlyPeriod, "2026-05-01", "2026-05"},
+		{QuarterlyPeriod, "2026-04-01", "2026-Q2"},
+		{YearlyPeriod, "2026-01-01", "2026"},
+	}
+	for _, test := range tests {
+		start, err := PeriodStart(test.period, at)
+		if err != nil {
+			t.Fatalf("PeriodStart(%s): %v", test.period, err)
+		}
+		if got := start.Format("2006-01-02"); got != test.start || start.Location() != time.UTC {
+			t.Errorf("PeriodStart(%s) = %s, want %s UTC", test.period, start, test.start)
+		}
+		if got := periodName(test.period, start); got != test.name {
+			t.Errorf("periodName(%s) = %s, want %s", test.period, got, test.name)
+		}
+	}
+	if _, err := PeriodStart("hourly", at); err != ErrInvalidPeriod {
+		t.Errorf("PeriodStart(hourly) = %v, want %v", err, ErrInvalidPeriod)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	// testLog mines block n on 2026-01-01 plus n days, so the block 31 is on February 1 and the block 70 on March 12
+	records := []Record{
+		receivedRecord(t, "0x1", 0, ownerAddress, "10"),
+		allowanceRecord(t, "0x2", 1, alice, "0", "4"),
+		allowanceRecord(t, "0x3", 2, alice, "4", "3"),
+		sentRecord(t, "0x3", 2, alice, "1"),
+		receivedRecord(t, "0x4", 31, bob, "5"),
+		allowanceRecord(t, "0x5", 32, bob, "0", "2"),
+		allowanceRecord(t, "0x6", 33, alice, "3", "1"),
+		sentRecord(t, "0x6", 33, alice, "2"),
+		sentRecord(t, "0x7", 70, bob, "2"),
+	}
+	type want struct {
+		name     string
+		opening  string
+		inflows  string
+		outflows string
+		closing  string
+		granted  string
+		spent    string
+		parties  int
+		funders  int
+	}
+	tests := []struct {
+		name   string
+		period string
+		from   time.Time
+		to     time.Time
+		want   []want
+	}{
+		{
+			name:   "monthly",
+			period: MonthlyPeriod,
+			from:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			to:     time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+			want: []want{
+				{"2026-01", "0", "10", "1", "9", "4", "1", 1, 1},
+				{"2026-02", "9", "5", "2", "12", "2", "2", 2, 1},
+				{"2026-03", "12", "0", "2", "10", "0", "2", 1, 0},
+			},
+		},
+		{
+			name:   "opening balance of a later period",
+			period: QuarterlyPeriod,
+			from:   time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC),
+			to:     time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC),
+			want: []want{
+				{"2026-Q1", "0", "15", "5", "10", "6", "5", 2, 2},
+			},
+		},
+		{
+			name:   "period without records",
+			period: MonthlyPeriod,
+			from:   time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+			to:     time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: []want{
+				{"2026-06", "10", "0", "0", "10", "0", "0", 0, 0},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := NewReport(records, test.period, test.from, test.to, nil)
+			if err != nil {
+				t.Fatalf("NewReport: %v", err)
+			}
+			if len(report) != len(test.want) {
+				t.Fatalf("got %d periods, want %d", len(report), len(test.want))
+			}
+			for i, want := range test.want {
+				got := report[i]
+				if got.Period != want.name {
+					t.Errorf("period %d = %s, want %s", i, got.Period, want.name)
+				}
+				amounts := []struct {
+					name string
+					got  *big.Int
+					want string
+				}{
+					{"opening balance", got.OpeningBalance, want.opening},
+					{"inflows", got.Inflows, want.inflows},
+					{"outflows", got.Outflows, want.outflows},
+					{"closing balance", got.ClosingBalance, want.closing},
+					{"allowance granted", got.AllowanceGranted, want.granted},
+					{"allowance spent", got.AllowanceSpent, want.spent},
+				}
+				for _, amount := range amounts {
+					if amount.got.Cmp(wei(t, amount.want)) != 0 {
+						t.Errorf("%s %s = %s, want %s", want.name, amount.name, FormatEther(amount.got), amount.want)
+					}
+				}
+				if len(got.Beneficiaries) != want.parties || len(got.Funders) != want.funders {
+					t.Errorf("%s has %d beneficiaries and %d funders, want %d and %d", want.name,
+						len(got.Beneficiaries), len(got.Funders), want.parties, want.funders)
+				}
+			}
+		})
+	}
+}
+
+func TestNewReportParties(t *testing.T) {
+	records := []Record{
+		receivedRecord(t, "0x1", 0, bob, "3"),
+		receivedRecord(t, "0x2", 1, "0x"+strings.ToUpper(bob[2:]), "2"),
+		allowanceRecord(t, "0x3", 2, bob, "0", "4"),
+		allowanceRecord(t, "0x4", 3, alice, "0", "1"),
+		sentRecord(t, "0x5", 4, bob, "1"),
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	report, err := NewReport(records, MonthlyPeriod, from, from, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funders := report[0].Funders
+	if len(funders) != 1 || funders[0].Inflows.Cmp(wei(t, "5")) != 0 || funders[0].Outflows != nil {
+		t.Errorf("funders = %+v, want bob with 5 ether of inflows", funders)
+	}
+	beneficiaries := report[0].Beneficiaries
+	if len(beneficiaries) != 2 || beneficiaries[0].Address > beneficiaries[1].Address {
+		t.Fatalf("beneficiaries = %+v, want alice and bob sorted by address", beneficiaries)
+	}
+	for _, b := range beneficiaries {
+		granted, sent := "1", "0"
+		if strings.EqualFold(b.Address, bob) {
+			granted, sent = "4", "1"
+		}
+		if b.AllowanceGranted.Cmp(wei(t, granted)) != 0 || b.Outflows.Cmp(wei(t, sent)) != 0 || b.Inflows != nil {
+			t.Errorf("%s = %+v, want %s ether granted and %s sent", b.Address, b, granted, sent)
+		}
+	}
+}
+
+func TestNewReportValues(t *testing.T) {
+	prices, err := price.Read(strings.NewReader("2025-12-31,100\n2026-01-15,150\n2026-02-01,200\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []Record{
+		receivedRecord(t, "0x1", 0, bob, "2"),
+		sentRecord(t, "0x2", 20, alice, "1"),
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	report, err := NewReport(records, MonthlyPeriod, from, from.AddDate(0, 1, 0), prices)
+	if err != nil {
+		t.Fatalf("NewReport: %v", err)
+	}
+	tests := []struct {
+		name string
+		got  *price.Value
+		want string
+	}{
+		// January: 2 ether received at 100, 1 sent at 150, 1 left valued at 150 on January 31
+		{"january opening", report[0].Fiat.OpeningBalance, "0.00"},
+		{"january inflows", report[0].Fiat.Inflows, "200.00"},
+		{"january outflows", report[0].Fiat.Outflows, "150.00"},
+		{"january closing", report[0].Fiat.ClosingBalance, "150.00"},
+		{"january revaluation", report[0].Fiat.Revaluation, "100.00"},
+		// February: no flows, 1 ether valued at 200 on February 28
+		{"february opening", report[1].Fiat.OpeningBalance, "150.00"},
+		{"february closing", report[1].Fiat.ClosingBalance, "200.00"},
+		{"february revaluation", report[1].Fiat.Revaluation, "50.00"},
+	}
+	for _, test := range tests {
+		if got := test.got.String(); got != test.want {
+			t.Errorf("%s = %s, want %s", test.name, got, test.want)
+		}
+	}
+
+	if _, err = NewReport(records, "hourly", from, from, nil); err != ErrInvalidPeriod {
+		t.Errorf("NewReport(hourly) = %v, want %v", err, ErrInvalidPeriod)
+	}
+}
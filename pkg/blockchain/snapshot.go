@@ -0,0 +1,170 @@
+package blockchain
+
+import (
+	"context"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const (
+	ContractChange = "contract"
+	OwnerChange = "owner"
+	BalanceChange = "balance"
+	AllowanceChange = "allowance"
+)
+
+// SnapshotBeneficiary struct, the allowance and the account balance in wei of a beneficiary
+type SnapshotBeneficiary struct {
+	Address string `json:"address"`
+	Allowance *big.Int `json:"allowance"`
+	Balance *big.Int `json:"balance"`
+}
+
+// Snapshot struct, the wallet state at a block with its amounts in wei. The beneficiaries are every address seen in the
+// AllowanceChanged events up to the block, with a zero allowance or not
+type Snapshot struct {
+	Contract string `json:"contract"`
+	Block uint64 `json:"block"`
+	BlockHash string `json:"block_hash"`
+	Time time.Time `json:"time"`
+	Owner string `json:"owner"`
+	Balance *big.Int `json:"balance"`
+	Beneficiaries []SnapshotBeneficiary `json:"beneficiaries"`
+}
+
+// SnapshotChange struct, a difference between two snapshots. The amount changes have the before and after amounts in
+// wei and the owner and contract changes the before and after addresses
+type SnapshotChange struct {
+	Subject string `json:"subject"`
+	Field string `json:"field"`
+	Before *big.Int `json:"before,omitempty"`
+	After *big.Int `json:"after,omitempty"`
+	Difference *big.Int `json:"difference,omitempty"`
+	PreviousAddress string `json:"previous_address,omitempty"`
+	NewAddress string `json:"new_address,omitempty"`
+}
+
+// TakeSnapshot reads the owner, the contract balance and the allowance and balance of every known beneficiary at a block
+func TakeSnapshot(ctx context.Context, client *ethclient.Client, contractAddress string, block BlockRef) (Snapshot, error) {
+	number, err := block.Resolve(ctx, client)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	header, err := client.HeaderByNumber(ctx, number)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	at := BlockRef{Number: number}
+	snapshot := Snapshot{
+		Contract: common.HexToAddress(contractAddress).Hex(),
+		Block: number.Uint64(),
+		BlockHash: header.Hash().Hex(),
+		Time: time.Unix(int64(header.Time), 0).UTC(),
+		Beneficiaries: []SnapshotBeneficiary{},
+	}
+	if snapshot.Owner, err = NewOwnerRunner("", contractAddress).GetOwner(ctx, client, at); err != nil {
+		return Snapshot{}, err
+	}
+	if snapshot.Balance, err = at.balanceAt(ctx, client, contractAddress); err != nil {
+		return Snapshot{}, err
+	}
+
+	history, err := NewHistory(client, contractAddress)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	records, err := history.Records(ctx, HistoryQuery{Types: []string{AllowanceChanged}, ToBlock: &snapshot.Block})
+	if err != nil {
+		return Snapshot{}, err
+	}
+	seen := map[string]bool{}
+	var beneficiaries []string
+	for _, record := range records {
+		if !seen[record.Beneficiary] {
+			seen[record.Beneficiary] = true
+			beneficiaries = append(beneficiaries, record.Beneficiary)
+		}
+	}
+	sort.Strings(beneficiaries)
+	state, err := NewContractState(client, contractAddress)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	allowances, err := state.At(number).Allowances(ctx, beneficiaries)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, beneficiary := range beneficiaries {
+		balance, err := at.balanceAt(ctx, client, beneficiary)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		snapshot.Beneficiaries = append(snapshot.Beneficiaries, SnapshotBeneficiary{
+			Address: beneficiary,
+			Allowance: allowances[beneficiary],
+			Balance: balance,
+		})
+	}
+	return snapshot, nil
+}
+
+// DiffSnapshots returns the changes from a snapshot to a later one, the beneficiaries missing in a snapshot have zero
+// amounts. The snapshots of different contracts are compared too, i.e. to check a migration
+func DiffSnapshots(before Snapshot, after Snapshot) []SnapshotChange {
+	var changes []SnapshotChange
+	if common.HexToAddress(before.Contract) != common.HexToAddress(after.Contract) {
+		changes = append(changes, SnapshotChange{Subject: after.Contract, Field: ContractChange,
+			PreviousAddress: before.Contract, NewAddress: after.Contract})
+	}
+	if common.HexToAddress(before.Owner) != common.HexToAddress(after.Owner) {
+		changes = append(changes, SnapshotChange{Subject: after.Contract, Field: OwnerChange,
+			PreviousAddress: before.Owner, NewAddress: after.Owner})
+	}
+	changes = appendAmountChange(changes, after.Contract, BalanceChange, before.Balance, after.Balance)
+
+	beneficiaries := map[common.Address][2]SnapshotBeneficiary{}
+	var addresses []common.Address
+	for i, snapshot := range []Snapshot{before, after} {
+		for _, beneficiary := range snapshot.Beneficiaries {
+			address := common.HexToAddress(beneficiary.Address)
+			pair, ok := beneficiaries[address]
+			if !ok {
+				addresses = append(addresses, address)
+			}
+			pair[i] = beneficiary
+			beneficiaries[address] = pair
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Hex() < addresses[j].Hex()
+	})
+	for _, address := range addresses {
+		pair := beneficiaries[address]
+		changes = appendAmountChange(changes, address.Hex(), AllowanceChange, pair[0].Allowance, pair[1].Allowance)
+		changes = appendAmountChange(changes, address.Hex(), BalanceChange, pair[0].Balance, pair[1].Balance)
+	}
+	return changes
+}
+
+// appendAmountChange appends the change of an amount when it differs, a nil amount is zero
+func appendAmountChange(changes []SnapshotChange, subject string, field string, before *big.Int, after *big.Int) []SnapshotChange {
+	if before == nil {
+		before = new(big.Int)
+	}
+	if after == nil {
+		after = new(big.Int)
+	}
+	if before.Cmp(after) == 0 {
+		return changes
+	}
+	return append(changes, SnapshotChange{
+		Subject: subject,
+		Field: field,
+		Before: before,
+		After: after,
+		Difference: new(big.Int).Sub(after, before),
+	})
+}
@@ -0,0 +1,115 @@
+package blockchain
+
+import (
+	"context"
+	contracts "github.com/StevenRojas/sharedWallet/contracts/interfaces"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"math/big"
+)
+
+// ContractState interface, it reads the contract balance and allowances in wei at the latest block or at the block
+// it is pinned to
+type ContractState interface {
+	At(block *big.Int) ContractState
+	Balance(ctx context.Context) (*big.Int, error)
+	Allowance(ctx context.Context, beneficiary string) (*big.Int, error)
+	Allowances(ctx context.Context, beneficiaries []string) (map[string]*big.Int, error)
+}
+
+// allowanceReaders number of concurrent allowance calls
+const allowanceReaders = 8
+
+type contractState struct {
+	address common.Address
+	client *ethclient.Client
+	caller *contracts.ContractCaller
+	block *big.Int
+}
+
+// NewContractState returns a reader of the contract state
+func NewContractState(client *ethclient.Client, contractAddress string) (ContractState, error) {
+	address := common.HexToAddress(contractAddress)
+	caller, err := contracts.NewContractCaller(address, client)
+	if err != nil {
+		return nil, err
+	}
+	return &contractState{
+		address: address,
+		client: client,
+		caller: caller,
+	}, nil
+}
+
+// At returns a reader of the contract state pinned to a block, the latest one if nil
+func (s *contractState) At(block *big.Int) ContractState {
+	pinned := *s
+	pinned.block = block
+	return &pinned
+}
+
+// Balance returns the contract balance in wei
+func (s *contractState) Balance(ctx context.Context) (*big.Int, error) {
+	balance, err := s.client.BalanceAt(ctx, s.address, s.block)
+	return balance, stateError(err)
+}
+
+// Allowance returns the allowance of a beneficiary in wei
+func (s *contractState) Allowance(ctx context.Context, beneficiary string) (*big.Int, error) {
+	allowance, err := s.caller.Allowance(&bind.CallOpts{Context: ctx, BlockNumber: s.block}, common.HexToAddress(beneficiary))
+	return allowance, stateError(err)
+}
+
+// Allowances returns the allowances of the beneficiaries in wei, they are read concurrently at the same block so
+// they are consistent with each other
+func (s *contractState) Allowances(ctx context.Context, beneficiaries []string) (map[string]*big.Int, error) {
+	block := s.block
+	if block == nil {
+		header, err := s.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		block = header.Number
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		beneficiary string
+		allowance *big.Int
+		err error
+	}
+	pending := make(chan string)
+	results := make(chan result)
+	for i := 0; i < allowanceReaders; i++ {
+		go func() {
+			for beneficiary := range pending {
+				allowance, err := s.caller.Allowance(&bind.CallOpts{Context: ctx, BlockNumber: block}, common.HexToAddress(beneficiary))
+				select {
+				case results <- result{beneficiary: beneficiary, allowance: allowance, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(pending)
+		for _, beneficiary := range beneficiaries {
+			select {
+			case pending <- beneficiary:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	allowances := make(map[string]*big.Int, len(beneficiaries))
+	for range beneficiaries {
+		r := <-results
+		if r.err != nil {
+			return nil, stateError(r.err)
+		}
+		allowances[r.beneficiary] = r.allowance
+	}
+	return allowances, nil
+}
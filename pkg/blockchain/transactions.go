@@ -2,27 +2,60 @@ package blockchain
 
 import (
 	"context"
+	"errors"
+	"github.com/StevenRojas/sharedWallet/pkg/metrics"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"math/big"
 )
 
+var ErrTransactionFailed = errors.New("transaction reverted")
+
+// TransactionInfo struct, the mined transaction with its gas and cost in wei
 type TransactionInfo struct {
 	Operation string   `json:"operation"`
+	TxHash    string   `json:"tx_hash"`
+	Block     uint64   `json:"block"`
 	Gas       uint64   `json:"gas"`
 	GasPrice  *big.Int `json:"gas_price"`
 	Cost      *big.Int `json:"cost"`
 }
 
-// processTransaction process the transaction in order to get stats
-func processTransaction(ctx context.Context, tx *types.Transaction, operation string) {
-	if tx == nil {
-		return
+// waitTransaction waits for the transaction to be mined, the transactions not sent, not mined or reverted are counted as failed.
+// The reverted transactions are returned with their info since they are mined
+func waitTransaction(ctx context.Context, client *ethclient.Client, tx *types.Transaction, txErr error, operation string) (TransactionInfo, error) {
+	if txErr != nil {
+		metrics.FailedTransactions.WithLabelValues(operation).Inc()
+		return TransactionInfo{}, txErr
+	}
+	receipt, err := bind.WaitMined(ctx, client, tx)
+	if err != nil {
+		metrics.FailedTransactions.WithLabelValues(operation).Inc()
+		return TransactionInfo{}, err
+	}
+	info := processTransaction(ctx, tx, receipt, operation)
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return info, ErrTransactionFailed
 	}
-	_ = TransactionInfo{
+	return info, nil
+}
+
+// processTransaction process the mined transaction in order to get stats, the reverted transactions also spend gas
+func processTransaction(_ context.Context, tx *types.Transaction, receipt *types.Receipt, operation string) TransactionInfo {
+	info := TransactionInfo{
 		Operation: operation,
-		Gas:       tx.Gas(),
+		TxHash:    tx.Hash().Hex(),
+		Block:     receipt.BlockNumber.Uint64(),
+		Gas:       receipt.GasUsed,
 		GasPrice:  tx.GasPrice(),
-		Cost:      tx.Cost(),
+		Cost:      new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tx.GasPrice()),
+	}
+	metrics.Transactions.WithLabelValues(operation).Inc()
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		metrics.FailedTransactions.WithLabelValues(operation).Inc()
 	}
-	// TODO: process the whole transaction and use if for stats
+	metrics.GasUsed.WithLabelValues(operation).Add(float64(info.Gas))
+	metrics.GasSpent.WithLabelValues(operation).Add(metrics.Wei(info.Cost))
+	return info
 }
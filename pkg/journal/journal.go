@@ -0,0 +1,216 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	BeancountFormat = "beancount"
+	LedgerFormat    = "ledger"
+
+	// GasAccount account of the gas fees
+	GasAccount = "Expenses:Gas"
+)
+
+var ErrInvalidFormat = errors.New("format should be beancount or ledger")
+
+// Accounts struct, it names the posting accounts after the address labels, the addresses without a label are used as
+// names
+type Accounts struct {
+	Labels map[string]string
+}
+
+// Wallet returns the account of a contract balance
+func (a Accounts) Wallet(contract string) string {
+	return "Assets:Wallet:" + a.name(contract)
+}
+
+// Funding returns the account of the money received from a funder
+func (a Accounts) Funding(funder string) string {
+	return "Income:Funding:" + a.name(funder)
+}
+
+// Allowances returns the account of the money sent to a beneficiary
+func (a Accounts) Allowances(beneficiary string) string {
+	return "Expenses:Allowances:" + a.name(beneficiary)
+}
+
+// Payer returns the account paying the gas of the transactions sent by an address
+func (a Accounts) Payer(address string) string {
+	return "Assets:Accounts:" + a.name(address)
+}
+
+// name returns the account name of an address, the label words are capitalized and joined by dashes
+func (a Accounts) name(address string) string {
+	label := ""
+	for key, value := range a.Labels {
+		if strings.EqualFold(key, address) {
+			label = value
+		}
+	}
+	words := strings.FieldsFunc(label, func(r rune) bool {
+		return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	if len(words) == 0 {
+		return address
+	}
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, "-")
+}
+
+// Posting struct, an amount in wei added to an account, the negative amounts leave it
+type Posting struct {
+	Account string
+	Amount  *big.Int
+}
+
+// Entry struct, the balanced postings of a transaction
+type Entry struct {
+	Time      time.Time
+	Block     uint64
+	TxHash    string
+	Narration string
+	Postings  []Posting
+}
+
+// NewJournal turns the MoneyReceived and MoneySent records of a contract into an entry by transaction, the gas fee of
+// the transactions with a cost is posted from the account of its payer
+func NewJournal(contract string, records []blockchain.Record, costs map[string]blockchain.GasCost, accounts Accounts) []Entry {
+	var entries []Entry
+	for _, record := range records {
+		var narration string
+		var postings []Posting
+		switch record.Type {
+		case blockchain.MoneyReceived:
+			narration = "Money received from " + accounts.name(record.Sender)
+			postings = []Posting{
+				{Account: accounts.Wallet(contract), Amount: record.Amount},
+				{Account: accounts.Funding(record.Sender), Amount: new(big.Int).Neg(record.Amount)},
+			}
+		case blockchain.MoneySent:
+			narration = "Money sent to " + accounts.name(record.Beneficiary)
+			postings = []Posting{
+				{Account: accounts.Allowances(record.Beneficiary), Amount: record.Amount},
+				{Account: accounts.Wallet(contract), Amount: new(big.Int).Neg(record.Amount)},
+			}
+		default:
+			continue
+		}
+		if n := len(entries); n > 0 && entries[n-1].TxHash == record.TxHash {
+			entries[n-1].Narration += "; " + narration
+			entries[n-1].Postings = append(entries[n-1].Postings, postings...)
+			continue
+		}
+		entries = append(entries, Entry{
+			Time:      record.Timestamp,
+			Block:     record.BlockNumber,
+			TxHash:    record.TxHash,
+			Narration: narration,
+			Postings:  postings,
+		})
+	}
+	for i, entry := range entries {
+		cost, ok := costs[entry.TxHash]
+		if !ok || cost.Fee.Sign() == 0 {
+			continue
+		}
+		entries[i].Postings = append(entries[i].Postings,
+			Posting{Account: GasAccount, Amount: cost.Fee},
+			Posting{Account: accounts.Payer(cost.Payer), Amount: new(big.Int).Neg(cost.Fee)},
+		)
+	}
+	return entries
+}
+
+// Write prints the entries in the beancount or ledger format, the amounts are in the commodity at full wei precision.
+// The beancount accounts are opened at the date of their first entry
+func Write(w io.Writer, entries []Entry, format string, commodity string) error {
+	switch format {
+	case BeancountFormat:
+		return writeBeancount(w, entries, commodity)
+	case LedgerFormat:
+		return writeLedger(w, entries, commodity)
+	}
+	return ErrInvalidFormat
+}
+
+func writeBeancount(w io.Writer, entries []Entry, commodity string) error {
+	opened := map[string]bool{}
+	var opens []string
+	for _, entry := range entries {
+		for _, posting := range entry.Postings {
+			if !opened[posting.Account] {
+				opened[posting.Account] = true
+				opens = append(opens, fmt.Sprintf("%s open %s %s", date(entry.Time, "2006-01-02"), posting.Account, commodity))
+			}
+		}
+	}
+	sort.Strings(opens)
+	for _, open := range opens {
+		if _, err := fmt.Fprintln(w, open); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		_, err := fmt.Fprintf(w, "\n%s * %q\n  block: %d\n  tx_hash: %q\n", date(entry.Time, "2006-01-02"), entry.Narration,
+			entry.Block, entry.TxHash)
+		if err != nil {
+			return err
+		}
+		if err = writePostings(w, entry.Postings, "  ", commodity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLedger(w io.Writer, entries []Entry, commodity string) error {
+	for i, entry := range entries {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s * %s\n    ; block: %d\n    ; tx_hash: %s\n", date(entry.Time, "2006/01/02"), entry.Narration,
+			entry.Block, entry.TxHash)
+		if err != nil {
+			return err
+		}
+		if err = writePostings(w, entry.Postings, "    ", commodity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePostings prints the postings with their amounts aligned
+func writePostings(w io.Writer, postings []Posting, indent string, commodity string) error {
+	width := 0
+	for _, posting := range postings {
+		if len(posting.Account) > width {
+			width = len(posting.Account)
+		}
+	}
+	for _, posting := range postings {
+		_, err := fmt.Fprintf(w, "%s%-*s  %s %s\n", indent, width, posting.Account, blockchain.FormatEther(posting.Amount), commodity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// date formats the day of an entry in UTC
+func date(t time.Time, layout string) string {
+	return t.UTC().Format(layout)
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	namespace = "wallet"
+	// shutdownTimeout time waiting for the running scrapes when the server is stopped
+	shutdownTimeout = 5 * time.Second
+)
+
+var (
+	// Registry holds the wallet metrics, the Go runtime metrics are not included
+	Registry = prometheus.NewRegistry()
+	// runnerRegistry holds the runner metrics pushed by the short lived commands
+	runnerRegistry = prometheus.NewRegistry()
+
+	// Events number of emitted events by contract and type
+	Events = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "events_total",
+		Help:      "Number of emitted events by contract and type.",
+	}, []string{"contract", "type"})
+	// LastBlock last block processed by the monitor
+	LastBlock = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "last_block",
+		Help:      "Last block processed by the monitor.",
+	}, []string{"contract"})
+	// HeadBlock last head of the chain seen by the monitor
+	HeadBlock = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "head_block",
+		Help:      "Head of the chain on the last refresh.",
+	}, []string{"contract"})
+	// Lag number of blocks between the head of the chain and the last processed block
+	Lag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "lag_blocks",
+		Help:      "Number of blocks between the head of the chain and the last processed block.",
+	}, []string{"contract"})
+	// Reconnects number of times the node connection was dialed again
+	Reconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "monitor",
+		Name:      "reconnects_total",
+		Help:      "Number of times the node connection was dialed again.",
+	}, []string{"endpoint"})
+	// SinkFailures number of events a sink was not able to deliver, after the retries or because its queue was full
+	SinkFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sink",
+		Name:      "failures_total",
+		Help:      "Number of events a sink was not able to deliver.",
+	}, []string{"sink"})
+	// ContractBalance contract balance in wei on the last refresh
+	ContractBalance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "contract",
+		Name:      "balance_wei",
+		Help:      "Contract balance in wei on the last refresh.",
+	}, []string{"contract"})
+	// Allowance beneficiary allowance in wei on the last refresh
+	Allowance = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "contract",
+		Name:      "allowance_wei",
+		Help:      "Beneficiary allowance in wei on the last refresh.",
+	}, []string{"contract", "beneficiary"})
+	// Transactions number of transactions sent by the runners by operation
+	Transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "runner",
+		Name:      "transactions_total",
+		Help:      "Number of transactions sent by operation.",
+	}, []string{"operation"})
+	// FailedTransactions number of transactions not sent, not mined or reverted by operation
+	FailedTransactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "runner",
+		Name:      "failed_transactions_total",
+		Help:      "Number of transactions not sent, not mined or reverted by operation.",
+	}, []string{"operation"})
+	// GasUsed gas used by the mined transactions by operation
+	GasUsed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "runner",
+		Name:      "gas_used_total",
+		Help:      "Gas used by the mined transactions by operation.",
+	}, []string{"operation"})
+	// GasSpent gas cost in wei of the mined transactions by operation
+	GasSpent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "runner",
+		Name:      "gas_spent_wei_total",
+		Help:      "Gas cost in wei of the mined transactions by operation.",
+	}, []string{"operation"})
+)
+
+func init() {
+	Registry.MustRegister(Events, LastBlock, HeadBlock, Lag, Reconnects, SinkFailures, ContractBalance, Allowance,
+		Transactions, FailedTransactions, GasUsed, GasSpent)
+	runnerRegistry.MustRegister(Transactions, FailedTransactions, GasUsed, GasSpent)
+}
+
+// Serve exposes the metrics in /metrics until the context is done
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctxShutdown)
+	}()
+	log.Printf("serving metrics at %s/metrics\n", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Push adds the runner metrics of a command run to a Prometheus Pushgateway in their own group, keyed by the host
+// and the run, since the Pushgateway replaces the metrics of a group instead of adding them up. The totals are the
+// sum of the groups
+func Push(url string, job string) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return push.New(url, job).
+		Gatherer(runnerRegistry).
+		Grouping("instance", host).
+		Grouping("run", strconv.FormatInt(time.Now().UnixNano(), 10)).
+		Add()
+}
+
+// Wei converts a wei amount to a metric value, big amounts lose precision
+func Wei(value *big.Int) float64 {
+	if value == nil {
+		return 0
+	}
+	result, _ := new(big.Float).SetInt(value).Float64()
+	return result
+}
@@ -0,0 +1,229 @@
+// Package output prints the command results as text, JSON, YAML, table or CSV. The amounts are integers in wei in
+// JSON and YAML, and decimal ether amounts without losing the wei precision in the text, table and csv outputs
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+const (
+	TextFormat  = "text"
+	JSONFormat  = "json"
+	YAMLFormat  = "yaml"
+	TableFormat = "table"
+	CSVFormat   = "csv"
+)
+
+var (
+	ErrInvalidFormat = errors.New("output should be text, json, yaml, table or csv")
+	ErrNotTabular    = errors.New("the result can't be printed as rows")
+)
+
+// Tabular interface implemented by the results printed as rows by the table and csv outputs, the rows are also their
+// text output when they don't have a Text method
+type Tabular interface {
+	Columns() []string
+	Rows() [][]string
+}
+
+// Texter interface implemented by the results with a human readable text output
+type Texter interface {
+	Text(w io.Writer) error
+}
+
+// Formatter interface
+type Formatter interface {
+	Write(w io.Writer, result interface{}) error
+}
+
+type formatter struct {
+	format string
+}
+
+// NewFormatter returns a formatter of the output format
+func NewFormatter(format string) (Formatter, error) {
+	if err := Validate(format); err != nil {
+		return nil, err
+	}
+	return &formatter{format: format}, nil
+}
+
+// Validate checks the output format
+func Validate(format string) error {
+	switch format {
+	case TextFormat, JSONFormat, YAMLFormat, TableFormat, CSVFormat:
+		return nil
+	}
+	return ErrInvalidFormat
+}
+
+// Print writes the result to the standard output in the output format
+func Print(format string, result interface{}) error {
+	f, err := NewFormatter(format)
+	if err != nil {
+		return err
+	}
+	return f.Write(os.Stdout, result)
+}
+
+// Write writes the result, JSON and YAML use the JSON fields of the result
+func (f *formatter) Write(w io.Writer, result interface{}) error {
+	switch f.format {
+	case JSONFormat:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case YAMLFormat:
+		return writeYAML(w, result)
+	case TextFormat:
+		if texter, ok := result.(Texter); ok {
+			return texter.Text(w)
+		}
+	}
+	tabular, ok := result.(Tabular)
+	if !ok {
+		return ErrNotTabular
+	}
+	if f.format == CSVFormat {
+		writer := csv.NewWriter(w)
+		_ = writer.Write(tabular.Columns())
+		_ = writer.WriteAll(tabular.Rows())
+		return writer.Error()
+	}
+	return WriteTable(w, tabular.Columns(), tabular.Rows())
+}
+
+// WriteTable prints the rows aligned in columns
+func WriteTable(w io.Writer, columns []string, rows [][]string) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, strings.Join(columns, "\t"))
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+// Stream interface, writes the results of a command that keeps printing them as they come
+type Stream interface {
+	Write(result interface{}) error
+}
+
+type stream struct {
+	w      io.Writer
+	format string
+	header bool
+	mu     sync.Mutex
+}
+
+// NewStream returns a stream of the output format. JSON results are written one by line and YAML ones as documents, the
+// table rows are separated by tabs since the columns widths are not known until the stream ends
+func NewStream(w io.Writer, format string) (Stream, error) {
+	if err := Validate(format); err != nil {
+		return nil, err
+	}
+	return &stream{w: w, format: format}, nil
+}
+
+// Write writes a result, the columns of the table and csv outputs are written before the first one
+func (s *stream) Write(result interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.format {
+	case JSONFormat:
+		return json.NewEncoder(s.w).Encode(result)
+	case YAMLFormat:
+		if _, err := io.WriteString(s.w, "---\n"); err != nil {
+			return err
+		}
+		return writeYAML(s.w, result)
+	case TextFormat:
+		if texter, ok := result.(Texter); ok {
+			return texter.Text(s.w)
+		}
+	}
+	tabular, ok := result.(Tabular)
+	if !ok {
+		return ErrNotTabular
+	}
+	writer := csv.NewWriter(s.w)
+	if s.format != CSVFormat {
+		writer.Comma = '\t'
+	}
+	if !s.header {
+		_ = writer.Write(tabular.Columns())
+		s.header = true
+	}
+	_ = writer.WriteAll(tabular.Rows())
+	return writer.Error()
+}
+
+// writeYAML writes the JSON fields of the result as YAML, keeping their order and the exact value of the large numbers
+func writeYAML(w io.Writer, result interface{}) error {
+	content, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	node, err := yamlNode(decoder)
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// yamlNode converts the next JSON value of the decoder into a YAML node
+func yamlNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch value := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if value == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if node.Kind == yaml.MappingNode {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			item, err := yamlNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, item)
+		}
+		// closing delimiter
+		_, err = decoder.Token()
+		return node, err
+	case json.Number:
+		// the tag is resolved from the value since the wei amounts overflow the YAML integers
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value.String()}, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
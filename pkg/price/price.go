@@ -0,0 +1,130 @@
+package price
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/ethereum/go-ethereum/params"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dateLayout layout of the price table dates
+const dateLayout = "2006-01-02"
+
+var (
+	ErrInvalidTable = errors.New("price table rows should be a YYYY-MM-DD date and a decimal price")
+	ErrMissingPrice = errors.New("there is no price on or before the date")
+)
+
+// Table interface, it values ether amounts with the fiat price of an ether by day. The price of a time is the one of its
+// UTC day, or the latest previous day when the day is missing
+type Table interface {
+	Price(t time.Time) (*big.Rat, error)
+	Value(wei *big.Int, t time.Time) (*Value, error)
+}
+
+type row struct {
+	date time.Time
+	price *big.Rat
+}
+
+type table struct {
+	rows []row
+}
+
+// Load reads a price table from a CSV file of date and price rows, the first row may be a header
+func Load(path string) (Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+// Read reads a price table from CSV date and price rows, the first row may be a header
+func Read(r io.Reader) (Table, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTable, err.Error())
+	}
+	t := &table{}
+	for i, record := range records {
+		date, err := time.Parse(dateLayout, strings.TrimSpace(record[0]))
+		if err != nil && i == 0 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidTable, i+1)
+		}
+		price, ok := new(big.Rat).SetString(strings.TrimSpace(record[1]))
+		if !ok || price.Sign() < 0 {
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidTable, i+1)
+		}
+		t.rows = append(t.rows, row{date: date, price: price})
+	}
+	sort.SliceStable(t.rows, func(i, j int) bool {
+		return t.rows[i].date.Before(t.rows[j].date)
+	})
+	return t, nil
+}
+
+// Price returns the price of an ether at a time
+func (t *table) Price(at time.Time) (*big.Rat, error) {
+	day := at.UTC()
+	i := sort.Search(len(t.rows), func(i int) bool {
+		return t.rows[i].date.After(day)
+	})
+	if i == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingPrice, day.Format(dateLayout))
+	}
+	return t.rows[i-1].price, nil
+}
+
+// Value returns the fiat value of a wei amount at a time
+func (t *table) Value(wei *big.Int, at time.Time) (*Value, error) {
+	price, err := t.Price(at)
+	if err != nil {
+		return nil, err
+	}
+	value := new(big.Rat).SetFrac(wei, big.NewInt(params.Ether))
+	return (*Value)(value.Mul(value, price)), nil
+}
+
+// Value fiat amount, it is encoded in JSON as a decimal number rounded to cents
+type Value big.Rat
+
+// NewValue returns a zero fiat amount
+func NewValue() *Value {
+	return (*Value)(new(big.Rat))
+}
+
+// Add adds an amount and returns the sum
+func (v *Value) Add(amount *Value) *Value {
+	(*big.Rat)(v).Add((*big.Rat)(v), (*big.Rat)(amount))
+	return v
+}
+
+// Sub subtracts an amount and returns the difference
+func (v *Value) Sub(amount *Value) *Value {
+	(*big.Rat)(v).Sub((*big.Rat)(v), (*big.Rat)(amount))
+	return v
+}
+
+// String returns the amount rounded to cents
+func (v *Value) String() string {
+	return (*big.Rat)(v).FloatString(2)
+}
+
+// MarshalJSON encodes the amount as a decimal number rounded to cents
+func (v *Value) MarshalJSON() ([]byte, error) {
+	return []byte(v.String()), nil
+}
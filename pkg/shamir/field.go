@@ -0,0 +1,47 @@
+package shamir
+
+// expTable and logTable are the exponential and logarithm tables of GF(2^8) using the AES
+// polynomial x^8 + x^4 + x^3 + x + 1 and 3 as generator
+var (
+	expTable [510]byte
+	logTable [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		expTable[i+255] = x
+		logTable[x] = byte(i)
+		x = add(x, xtime(x))
+	}
+}
+
+// xtime multiplies by x (2) in GF(2^8)
+func xtime(a byte) byte {
+	if a&0x80 != 0 {
+		return a<<1 ^ 0x1b
+	}
+	return a << 1
+}
+
+// add adds two field elements, it is also the subtraction
+func add(a byte, b byte) byte {
+	return a ^ b
+}
+
+// mul multiplies two field elements
+func mul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+// div divides two field elements, b should not be zero
+func div(a byte, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
@@ -0,0 +1,107 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+)
+
+var (
+	ErrInvalidThreshold = errors.New("threshold should be between 2 and the number of shares")
+	ErrInvalidShares    = errors.New("number of shares should be between 2 and 255")
+	ErrEmptySecret      = errors.New("secret cannot be empty")
+	ErrNotEnoughShares  = errors.New("at least two shares are required")
+	ErrInvalidShare     = errors.New("invalid share")
+	ErrDuplicateShare   = errors.New("duplicate share")
+)
+
+// Split divides the secret in the given number of shares, any threshold of them can rebuild the secret.
+// Every share has the same length as the secret plus one trailing byte with the share coordinate
+func Split(secret []byte, shares int, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, ErrEmptySecret
+	}
+	if shares < 2 || shares > 255 {
+		return nil, ErrInvalidShares
+	}
+	if threshold < 2 || threshold > shares {
+		return nil, ErrInvalidThreshold
+	}
+
+	result := make([][]byte, shares)
+	for i := range result {
+		result[i] = make([]byte, len(secret)+1)
+		result[i][len(secret)] = byte(i + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+	for i, value := range secret {
+		coefficients[0] = value
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for _, share := range result {
+			share[i] = evaluate(coefficients, share[len(secret)])
+		}
+	}
+	return result, nil
+}
+
+// Combine rebuilds the secret from a set of shares generated by Split
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, ErrNotEnoughShares
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, ErrInvalidShare
+	}
+
+	xs := make([]byte, len(shares))
+	seen := map[byte]struct{}{}
+	for i, share := range shares {
+		if len(share) != size || share[size-1] == 0 {
+			return nil, ErrInvalidShare
+		}
+		x := share[size-1]
+		if _, ok := seen[x]; ok {
+			return nil, ErrDuplicateShare
+		}
+		seen[x] = struct{}{}
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(shares))
+	for i := range secret {
+		for j, share := range shares {
+			ys[j] = share[i]
+		}
+		secret[i] = interpolate(xs, ys)
+	}
+	return secret, nil
+}
+
+// evaluate evaluates the polynomial at x using the Horner's method
+func evaluate(coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = add(mul(result, x), coefficients[i])
+	}
+	return result
+}
+
+// interpolate gets the polynomial value at x = 0 using Lagrange interpolation
+func interpolate(xs []byte, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			basis = mul(basis, div(xs[j], add(xs[i], xs[j])))
+		}
+		result = add(result, mul(ys[i], basis))
+	}
+	return result
+}
@@ -0,0 +1,219 @@
+package shamir
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// slowMul multiplies two field elements with the shift and add method, it checks the log tables
+func slowMul(a byte, b byte) byte {
+	var result byte
+	for b > 0 {
+		if b&1 != 0 {
+			result = add(result, a)
+		}
+		a = xtime(a)
+		b >>= 1
+	}
+	return result
+}
+
+func TestFieldArithmetic(t *testing.T) {
+	// known products of the AES field
+	tests := []struct {
+		a, b, want byte
+	}{
+		{0x57, 0x83, 0xc1},
+		{0x57, 0x13, 0xfe},
+		{0x02, 0x87, 0x15},
+		{0x00, 0x53, 0x00},
+		{0x53, 0x01, 0x53},
+		{0x53, 0xca, 0x01},
+	}
+	for _, test := range tests {
+		if got := mul(test.a, test.b); got != test.want {
+			t.Errorf("mul(%#x, %#x) = %#x, want %#x", test.a, test.b, got, test.want)
+		}
+	}
+
+	for a := 0; a < 256; a++ {
+		for b := 0; b < 256; b++ {
+			x, y := byte(a), byte(b)
+			if got, want := mul(x, y), slowMul(x, y); got != want {
+				t.Fatalf("mul(%#x, %#x) = %#x, want %#x", x, y, got, want)
+			}
+			if add(add(x, y), y) != x {
+				t.Fatalf("add(%#x, %#x) is not its own inverse", x, y)
+			}
+			if y != 0 && mul(div(x, y), y) != x {
+				t.Fatalf("div(%#x, %#x) is not the inverse of mul", x, y)
+			}
+		}
+	}
+}
+
+func TestEvaluateAndInterpolate(t *testing.T) {
+	coefficients := []byte{0x2a, 0x11, 0xf0}
+	xs := []byte{1, 2, 3}
+	ys := make([]byte, len(xs))
+	for i, x := range xs {
+		ys[i] = evaluate(coefficients, x)
+	}
+	if got := evaluate(coefficients, 0); got != coefficients[0] {
+		t.Errorf("evaluate at 0 = %#x, want %#x", got, coefficients[0])
+	}
+	if got := interpolate(xs, ys); got != coefficients[0] {
+		t.Errorf("interpolate = %#x, want %#x", got, coefficients[0])
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	tests := []struct {
+		name      string
+		shares    int
+		threshold int
+		use       []int
+	}{
+		{"minimum", 2, 2, []int{0, 1}},
+		{"k below n", 3, 2, []int{2, 0}},
+		{"k equals n", 5, 5, []int{0, 1, 2, 3, 4}},
+		{"more than k shares", 5, 3, []int{4, 1, 3, 0}},
+		{"last shares", 5, 3, []int{2, 3, 4}},
+		{"max shares", 255, 4, []int{254, 100, 7, 31}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shares, err := Split(secret, test.shares, test.threshold)
+			if err != nil {
+				t.Fatalf("Split: %v", err)
+			}
+			if len(shares) != test.shares {
+				t.Fatalf("got %d shares, want %d", len(shares), test.shares)
+			}
+			for i, share := range shares {
+				if len(share) != len(secret)+1 || share[len(secret)] != byte(i+1) {
+					t.Fatalf("share %d has an invalid layout", i)
+				}
+			}
+			subset := make([][]byte, 0, len(test.use))
+			for _, i := range test.use {
+				subset = append(subset, shares[i])
+			}
+			got, err := Combine(subset)
+			if err != nil {
+				t.Fatalf("Combine: %v", err)
+			}
+			if !bytes.Equal(got, secret) {
+				t.Errorf("Combine = %q, want %q", got, secret)
+			}
+		})
+	}
+}
+
+func TestCombineFewerThanThreshold(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x5a}, 32)
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Combine(shares[:2])
+	if err != nil {
+		t.Fatalf("Combine: %v", err)
+	}
+	if bytes.Equal(got, secret) {
+		t.Error("two shares of a threshold of three rebuilt the secret")
+	}
+	if _, err = Combine(shares[:1]); !errors.Is(err, ErrNotEnoughShares) {
+		t.Errorf("Combine of one share = %v, want %v", err, ErrNotEnoughShares)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    []byte
+		shares    int
+		threshold int
+		err       error
+	}{
+		{"empty secret", nil, 3, 2, ErrEmptySecret},
+		{"one share", []byte{1}, 1, 1, ErrInvalidShares},
+		{"too many shares", []byte{1}, 256, 2, ErrInvalidShares},
+		{"k equals one", []byte{1}, 3, 1, ErrInvalidThreshold},
+		{"k above n", []byte{1}, 3, 4, ErrInvalidThreshold},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Split(test.secret, test.shares, test.threshold); !errors.Is(err, test.err) {
+				t.Errorf("Split = %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestCombineErrors(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero := append([]byte{}, shares[1]...)
+	zero[len(zero)-1] = 0
+	tests := []struct {
+		name   string
+		shares [][]byte
+		err    error
+	}{
+		{"no shares", nil, ErrNotEnoughShares},
+		{"short share", [][]byte{{1}, {2}}, ErrInvalidShare},
+		{"different lengths", [][]byte{shares[0], shares[1][1:]}, ErrInvalidShare},
+		{"zero coordinate", [][]byte{shares[0], zero}, ErrInvalidShare},
+		{"duplicate share", [][]byte{shares[0], shares[0]}, ErrDuplicateShare},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Combine(test.shares); !errors.Is(err, test.err) {
+				t.Errorf("Combine = %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestWords(t *testing.T) {
+	if len(wordIndex) != len(wordList) {
+		t.Fatalf("the word list has %d distinct words, want %d", len(wordIndex), len(wordList))
+	}
+	share := make([]byte, 256)
+	for i := range share {
+		share[i] = byte(i)
+	}
+	words := ToWords(share)
+	if fields := strings.Fields(words); len(fields) != len(share)+1 {
+		t.Fatalf("got %d words, want %d", len(fields), len(share)+1)
+	}
+	got, err := FromWords(strings.ToUpper("  " + words + "\n"))
+	if err != nil {
+		t.Fatalf("FromWords: %v", err)
+	}
+	if !bytes.Equal(got, share) {
+		t.Error("FromWords does not return the encoded share")
+	}
+
+	fields := strings.Fields(words)
+	swapped := append([]string{}, fields...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	invalid := []string{
+		"",
+		fields[0],
+		words + " " + fields[0],
+		strings.Join(swapped, " "),
+		strings.Replace(words, fields[3], "notaword", 1),
+	}
+	for _, value := range invalid {
+		if _, err := FromWords(value); !errors.Is(err, ErrInvalidWords) {
+			t.Errorf("FromWords(%.20q) = %v, want %v", value, err, ErrInvalidWords)
+		}
+	}
+}
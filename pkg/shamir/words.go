@@ -0,0 +1,90 @@
+package shamir
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidWords = errors.New("invalid share words")
+
+// wordList maps every byte value to a word, the list is sorted so the index of a word is its byte value
+var wordList = [256]string{
+	"able", "acid", "aged", "also", "area", "army", "away", "baby",
+	"back", "ball", "band", "bank", "base", "bath", "bear", "beat",
+	"been", "beer", "bell", "belt", "best", "bill", "bird", "blow",
+	"blue", "boat", "body", "bond", "bone", "book", "boom", "born",
+	"boss", "both", "bowl", "bulk", "burn", "bush", "busy", "cake",
+	"call", "calm", "came", "camp", "card", "care", "case", "cash",
+	"cast", "cell", "chat", "chip", "city", "club", "coal", "coat",
+	"code", "cold", "come", "cook", "cool", "cope", "copy", "core",
+	"cost", "crew", "crop", "dark", "data", "date", "dawn", "days",
+	"deal", "dear", "debt", "deep", "deny", "desk", "dial", "diet",
+	"disc", "disk", "does", "done", "door", "dose", "down", "draw",
+	"drew", "drop", "dual", "duke", "dust", "duty", "each", "earn",
+	"ease", "east", "easy", "edge", "else", "even", "ever", "exit",
+	"face", "fact", "fail", "fair", "fall", "farm", "fast", "fate",
+	"fear", "feed", "feel", "feet", "fell", "felt", "file", "fill",
+	"film", "find", "fine", "fire", "firm", "fish", "five", "flat",
+	"flow", "food", "foot", "form", "fort", "four", "free", "from",
+	"fuel", "full", "fund", "gain", "game", "gate", "gave", "gear",
+	"gift", "girl", "give", "glad", "goal", "goes", "gold", "golf",
+	"gone", "good", "gray", "grew", "grey", "grow", "gulf", "hair",
+	"half", "hall", "hand", "hang", "hard", "have", "head", "hear",
+	"heat", "held", "help", "here", "hero", "high", "hill", "hire",
+	"hold", "hole", "holy", "home", "hope", "host", "hour", "huge",
+	"hung", "hunt", "idea", "inch", "into", "iron", "item", "jack",
+	"join", "jump", "jury", "just", "keen", "keep", "kept", "kick",
+	"kind", "king", "knee", "knew", "know", "lack", "lady", "laid",
+	"lake", "land", "lane", "last", "late", "lead", "left", "less",
+	"life", "lift", "like", "line", "link", "list", "live", "load",
+	"loan", "lock", "logo", "long", "look", "lord", "lose", "loss",
+	"lost", "love", "luck", "made", "mail", "main", "make", "male",
+	"many", "mark", "mass", "meal", "mean", "meat", "meet", "menu",
+	"mere", "mile", "milk", "mill", "mind", "mine", "miss", "mode",
+}
+
+var wordIndex = func() map[string]byte {
+	index := make(map[string]byte, len(wordList))
+	for i, word := range wordList {
+		index[word] = byte(i)
+	}
+	return index
+}()
+
+// ToWords encodes a share as a list of words separated by spaces, a checksum word is appended at the end
+func ToWords(share []byte) string {
+	words := make([]string, 0, len(share)+1)
+	for _, b := range share {
+		words = append(words, wordList[b])
+	}
+	words = append(words, wordList[checksum(share)])
+	return strings.Join(words, " ")
+}
+
+// FromWords decodes a share encoded by ToWords validating its checksum word
+func FromWords(words string) ([]byte, error) {
+	fields := strings.Fields(strings.ToLower(words))
+	if len(fields) < 2 {
+		return nil, ErrInvalidWords
+	}
+	share := make([]byte, len(fields))
+	for i, field := range fields {
+		b, ok := wordIndex[field]
+		if !ok {
+			return nil, ErrInvalidWords
+		}
+		share[i] = b
+	}
+	data := share[:len(share)-1]
+	if checksum(data) != share[len(share)-1] {
+		return nil, ErrInvalidWords
+	}
+	return data, nil
+}
+
+// checksum returns the first byte of the share hash
+func checksum(share []byte) byte {
+	sum := sha256.Sum256(share)
+	return sum[0]
+}
@@ -0,0 +1,63 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var ErrMissingCommand = errors.New("exec sink command is required")
+
+type execSink struct {
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+// NewExec returns a sink that runs a command for every event with its JSON on stdin,
+// the event type is also set in the WALLET_EVENT environment variable. The command must be found in the path
+func NewExec(command string, args []string, timeout time.Duration) (EventSink, error) {
+	if command == "" {
+		return nil, ErrMissingCommand
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return nil, fmt.Errorf("exec sink: %w", err)
+	}
+	return &execSink{
+		command: command,
+		args:    args,
+		timeout: timeout,
+	}, nil
+}
+
+// Write runs the command, a non zero exit code is an error
+func (e *execSink) Write(ctx context.Context, event blockchain.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.command, e.args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "WALLET_EVENT="+event.Type())
+	if err = cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// Close does nothing
+func (e *execSink) Close() error {
+	return nil
+}
@@ -0,0 +1,341 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"github.com/StevenRojas/sharedWallet/config"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/metrics"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	StdoutSink  = "stdout"
+	FileSink    = "file"
+	WebhookSink = "webhook"
+	ExecSink    = "exec"
+
+	defaultTimeout   = 10 * time.Second
+	defaultRetries   = 3
+	defaultBackoff   = time.Second
+	defaultQueueSize = 1024
+	// defaultQueueTimeout maximum time a write waits for room in the queue of a durable sink before dropping the event
+	defaultQueueTimeout = 5 * time.Second
+	// maxBackoff maximum time between two attempts
+	maxBackoff = time.Minute
+	// drainTimeout maximum time waiting for the queued events when the fanout is closed, the durable sinks still
+	// get one attempt for every queued event after it
+	drainTimeout = 2 * time.Second
+)
+
+var (
+	ErrInvalidSinkType = errors.New("invalid sink type")
+	ErrQueueFull       = errors.New("sink queue is full, event dropped")
+	ErrFanoutClosed    = errors.New("sink fanout is closed")
+)
+
+// EventSink interface implemented by the event destinations
+type EventSink interface {
+	blockchain.EventSink
+	io.Closer
+}
+
+// New creates a sink from its configuration
+func New(cfg config.SinkConfig) (EventSink, error) {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	switch cfg.Type {
+	case StdoutSink:
+		if cfg.Format == "" {
+			return NewStdout(output.JSONFormat)
+		}
+		return NewStdout(cfg.Format)
+	case FileSink:
+		return NewFile(cfg.Path, cfg.MaxSizeMB*1024*1024, cfg.MaxFiles)
+	case WebhookSink:
+		return NewWebhook(cfg.URL, cfg.Secret, cfg.Timeout)
+	case ExecSink:
+		return NewExec(cfg.Command, cfg.Args, cfg.Timeout)
+	}
+	return nil, ErrInvalidSinkType
+}
+
+// job queued in a worker, an event to deliver or a flush request answered once the previous events are delivered
+type job struct {
+	event   blockchain.Event
+	flushed chan error
+}
+
+// worker delivers the events of a sink from its own queue, retrying them with exponential backoff.
+// A durable worker waits for room in its queue up to the queue timeout and acknowledges the events on flush
+type worker struct {
+	name         string
+	sink         EventSink
+	queue        chan job
+	retries      int
+	backoff      time.Duration
+	durable      bool
+	queueTimeout time.Duration
+	// failed first event dropped by a durable sink, the following flushes fail so the checkpoint does not move past it
+	failed error
+	mutex  sync.Mutex
+}
+
+// Fanout struct, it sends every event to all the sinks concurrently so a slow sink does not block the others
+type Fanout struct {
+	workers []*worker
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	once    sync.Once
+	// closing is held while queueing so the queues are not closed under a blocked write, done is set under it on close
+	closing sync.RWMutex
+	closed  chan struct{}
+	done    bool
+}
+
+// NewFanout creates the configured sinks and starts their workers, a stdout sink is used if there is no configuration.
+// The stdout sinks without a format use the given one
+func NewFanout(configs []config.SinkConfig, stdoutFormat string) (*Fanout, error) {
+	if len(configs) == 0 {
+		configs = []config.SinkConfig{{Type: StdoutSink}}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &Fanout{ctx: ctx, cancel: cancel, closed: make(chan struct{})}
+	for _, cfg := range configs {
+		if cfg.Type == StdoutSink && cfg.Format == "" {
+			cfg.Format = stdoutFormat
+		}
+		sink, err := New(cfg)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		f.add(cfg.Type, sink, cfg, cfg.Durable)
+	}
+	return f, nil
+}
+
+// Add starts a worker for a sink created outside the configuration, i.e. the event store. The checkpoint waits for
+// the durable sinks to acknowledge the events
+func (f *Fanout) Add(name string, sink EventSink, durable bool) {
+	f.add(name, sink, config.SinkConfig{}, durable)
+}
+
+// add starts the worker of a sink with the queue and retries of its configuration
+func (f *Fanout) add(name string, sink EventSink, cfg config.SinkConfig, durable bool) {
+	w := &worker{
+		name:         name,
+		sink:         sink,
+		queue:        make(chan job, valueOr(cfg.QueueSize, defaultQueueSize)),
+		retries:      valueOr(cfg.Retries, defaultRetries),
+		backoff:      cfg.Backoff,
+		durable:      durable,
+		queueTimeout: cfg.QueueTimeout,
+	}
+	if w.backoff == 0 {
+		w.backoff = defaultBackoff
+	}
+	if w.queueTimeout == 0 {
+		w.queueTimeout = defaultQueueTimeout
+	}
+	f.workers = append(f.workers, w)
+	f.wg.Add(1)
+	go f.run(w)
+}
+
+// Write queues the event in every sink, it waits for room in the queues of the durable sinks up to their queue timeout
+// and drops the event when the queue of other sink is full. A durable sink dropping an event fails its next flushes
+func (f *Fanout) Write(ctx context.Context, event blockchain.Event) error {
+	f.closing.RLock()
+	defer f.closing.RUnlock()
+	if f.done {
+		return ErrFanoutClosed
+	}
+	var err error
+	for _, w := range f.workers {
+		if w.durable {
+			if errQueue := f.enqueue(ctx, w, job{event: event}); errQueue != nil {
+				log.Printf("%s sink: unable to queue %s event: %s\n", w.name, event.Type(), errQueue.Error())
+				metrics.SinkFailures.WithLabelValues(w.name).Inc()
+				w.fail(errQueue)
+				err = errQueue
+			}
+			continue
+		}
+		select {
+		case w.queue <- job{event: event}:
+		default:
+			log.Printf("%s sink: %s\n", w.name, ErrQueueFull.Error())
+			metrics.SinkFailures.WithLabelValues(w.name).Inc()
+			err = ErrQueueFull
+		}
+	}
+	return err
+}
+
+// Flush waits until the durable sinks deliver the events written before it and flushes the sinks that batch them,
+// it fails if any of those events was not delivered
+func (f *Fanout) Flush(ctx context.Context) error {
+	f.closing.RLock()
+	if f.done {
+		f.closing.RUnlock()
+		return ErrFanoutClosed
+	}
+	var pending []chan error
+	for _, w := range f.workers {
+		if !w.durable {
+			continue
+		}
+		flushed := make(chan error, 1)
+		if err := f.enqueue(ctx, w, job{flushed: flushed}); err != nil {
+			f.closing.RUnlock()
+			return err
+		}
+		pending = append(pending, flushed)
+	}
+	f.closing.RUnlock()
+	for _, flushed := range pending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-flushed:
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// enqueue waits for room in the queue of a worker up to its queue timeout, the caller holds the closing lock
+func (f *Fanout) enqueue(ctx context.Context, w *worker, j job) error {
+	timer := time.NewTimer(w.queueTimeout)
+	defer timer.Stop()
+	select {
+	case w.queue <- j:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-f.closed:
+		return ErrFanoutClosed
+	case <-timer.C:
+		return ErrQueueFull
+	}
+}
+
+// Close waits for the queued events to be delivered, up to the drain timeout, and closes the sinks.
+// The durable sinks get an attempt for their remaining events after the timeout
+func (f *Fanout) Close() error {
+	f.once.Do(func() {
+		close(f.closed)
+		f.closing.Lock()
+		f.done = true
+		for _, w := range f.workers {
+			close(w.queue)
+		}
+		f.closing.Unlock()
+		done := make(chan struct{})
+		go func() {
+			f.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(drainTimeout):
+			f.cancel()
+			<-done
+		}
+		f.cancel()
+		for _, w := range f.workers {
+			if err := w.sink.Close(); err != nil {
+				log.Printf("%s sink: %s\n", w.name, err.Error())
+			}
+		}
+	})
+	return nil
+}
+
+// run delivers the queued events of a sink until its queue is closed
+func (f *Fanout) run(w *worker) {
+	defer f.wg.Done()
+	for j := range w.queue {
+		if j.flushed != nil {
+			j.flushed <- f.flush(w)
+			continue
+		}
+		if f.ctx.Err() != nil && !w.durable {
+			continue
+		}
+		if err := f.deliver(w, j.event); err != nil {
+			log.Printf("%s sink: unable to deliver %s event, dropped: %s\n", w.name, j.event.Type(), err.Error())
+			metrics.SinkFailures.WithLabelValues(w.name).Inc()
+			w.fail(err)
+		}
+	}
+}
+
+// flush returns the error of the first event dropped by the worker or flushes its sink when it batches the events
+func (f *Fanout) flush(w *worker) error {
+	if err := w.err(); err != nil {
+		return err
+	}
+	if flusher, ok := w.sink.(blockchain.EventFlusher); ok {
+		return flusher.Flush(f.ctx)
+	}
+	return nil
+}
+
+// deliver writes the event retrying it with exponential backoff up to the worker retries or until the fanout is closed
+func (f *Fanout) deliver(w *worker, event blockchain.Event) error {
+	backoff := w.backoff
+	var err error
+	for attempt := 0; attempt <= w.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-f.ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		if err = w.sink.Write(f.ctx, event); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// fail keeps the first event error of a durable worker
+func (w *worker) fail(err error) {
+	if !w.durable {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.failed == nil {
+		w.failed = err
+	}
+}
+
+// err returns the first event error of the worker
+func (w *worker) err() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.failed
+}
+
+// valueOr returns the value or the default one when it is not set
+func valueOr(value int, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
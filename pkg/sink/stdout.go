@@ -0,0 +1,73 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/output"
+	"github.com/StevenRojas/sharedWallet/pkg/store"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type stdout struct {
+	stream output.Stream
+}
+
+// NewStdout returns a sink that prints the events in the output format, the text output is a line of fields by event
+func NewStdout(format string) (EventSink, error) {
+	stream, err := output.NewStream(os.Stdout, format)
+	if err != nil {
+		return nil, err
+	}
+	return &stdout{stream: stream}, nil
+}
+
+// Write prints the event
+func (s *stdout) Write(_ context.Context, event blockchain.Event) error {
+	return s.stream.Write(printedEvent{event})
+}
+
+// Close does nothing
+func (s *stdout) Close() error {
+	return nil
+}
+
+// printedEvent the event written to the output stream
+type printedEvent struct {
+	blockchain.Event
+}
+
+// Columns returns the event columns
+func (e printedEvent) Columns() []string {
+	return store.EventColumns
+}
+
+// Rows returns the event row
+func (e printedEvent) Rows() [][]string {
+	return [][]string{store.EventRow(e.Event)}
+}
+
+// Text prints the non-empty columns of the event as name=value fields, the values with spaces are quoted
+func (e printedEvent) Text(w io.Writer) error {
+	var fields []string
+	for i, value := range store.EventRow(e.Event) {
+		if value == "" {
+			continue
+		}
+		if strings.ContainsAny(value, " \"=") {
+			value = strconv.Quote(value)
+		}
+		fields = append(fields, store.EventColumns[i]+"="+value)
+	}
+	_, err := fmt.Fprintln(w, strings.Join(fields, " "))
+	return err
+}
+
+// MarshalJSON returns the event
+func (e printedEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.Event)
+}
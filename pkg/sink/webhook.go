@@ -0,0 +1,83 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var ErrInvalidURL = errors.New("webhook sink url should be an http or https URL")
+
+const (
+	// SignatureHeader header with the HMAC SHA256 signature of the body, as sha256=<hex>
+	SignatureHeader = "X-Wallet-Signature"
+	// EventTypeHeader header with the event type
+	EventTypeHeader = "X-Wallet-Event"
+)
+
+type webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhook returns a sink that posts the events as JSON to an http or https URL, the body is signed when there is
+// a secret
+func NewWebhook(address string, secret string, timeout time.Duration) (EventSink, error) {
+	parsed, err := url.Parse(address)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidURL, address)
+	}
+	return &webhook{
+		url:    address,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Write posts the event, any status other than 2xx is an error
+func (w *webhook) Write(ctx context.Context, event blockchain.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventTypeHeader, event.Type())
+	if len(w.secret) > 0 {
+		request.Header.Set(SignatureHeader, "sha256="+Sign(w.secret, body))
+	}
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Close does nothing
+func (w *webhook) Close() error {
+	return nil
+}
+
+// Sign returns the hex HMAC SHA256 of the body
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
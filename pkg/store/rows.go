@@ -0,0 +1,58 @@
+package store
+
+import (
+	"encoding/json"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"strconv"
+	"time"
+)
+
+// EventColumns columns of the event rows
+var EventColumns = []string{"block", "log_index", "timestamp", "tx_hash", "contract", "label", "event_type", "sender", "beneficiary", "amount"}
+
+// Events the events printed as rows, the amounts of the rows are in ether
+type Events []blockchain.Event
+
+// Columns returns the event columns
+func (e Events) Columns() []string {
+	return EventColumns
+}
+
+// Rows returns the event rows
+func (e Events) Rows() [][]string {
+	rows := make([][]string, 0, len(e))
+	for _, event := range e {
+		rows = append(rows, EventRow(event))
+	}
+	return rows
+}
+
+// MarshalJSON returns the events, an empty list is an empty array
+func (e Events) MarshalJSON() ([]byte, error) {
+	if e == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]blockchain.Event(e))
+}
+
+// EventRow returns the values of the event columns
+func EventRow(event blockchain.Event) []string {
+	eventLog := event.Log()
+	beneficiary, sender := Parties(event)
+	amount := ""
+	if value := Amount(event); value != nil {
+		amount = blockchain.FormatEther(value)
+	}
+	return []string{
+		strconv.FormatUint(eventLog.BlockNumber, 10),
+		strconv.FormatUint(uint64(eventLog.LogIndex), 10),
+		eventLog.Timestamp.UTC().Format(time.RFC3339),
+		eventLog.TxHash,
+		eventLog.Contract,
+		eventLog.Label,
+		event.Type(),
+		sender,
+		beneficiary,
+		amount,
+	}
+}
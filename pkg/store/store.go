@@ -0,0 +1,319 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/ethereum/go-ethereum/common"
+	bolt "go.etcd.io/bbolt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+var (
+	eventsBucket      = []byte("events")
+	typeBucket        = []byte("type")
+	beneficiaryBucket = []byte("beneficiary")
+	senderBucket      = []byte("sender")
+
+	ErrMissingPath = errors.New("event store path is required")
+)
+
+// openTimeout time waiting for the file lock, i.e. when a monitor is writing the store
+const openTimeout = 5 * time.Second
+
+// Query struct, the zero value fields are not used to filter
+type Query struct {
+	Contract    string
+	Type        string
+	Beneficiary string
+	Sender      string
+	Since       time.Time
+	Until       time.Time
+	FromBlock   uint64
+	ToBlock     uint64
+	Limit       int
+}
+
+// Store interface
+type Store interface {
+	Write(ctx context.Context, event blockchain.Event) error
+	Flush(ctx context.Context) error
+	Query(query Query) ([]blockchain.Event, error)
+	Close() error
+}
+
+// batched event waiting for the next flush with its JSON value
+type batched struct {
+	event blockchain.Event
+	value []byte
+}
+
+type store struct {
+	db    *bolt.DB
+	mutex sync.Mutex
+	batch []batched
+}
+
+// Open opens or creates the event store file
+func Open(path string, readOnly bool) (Store, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return nil, err
+	}
+	if !readOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{eventsBucket, typeBucket, beneficiaryBucket, senderBucket} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	return &store{db: db}, nil
+}
+
+// Write adds the event to the batch saved on the next flush
+func (s *store) Write(_ context.Context, event blockchain.Event) error {
+	var value []byte
+	if _, ok := event.(*blockchain.RetractedEvent); !ok {
+		var err error
+		if value, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batch = append(s.batch, batched{event: event, value: value})
+	return nil
+}
+
+// Flush saves the batched events and their indexes in a single transaction, a retracted event deletes the event it
+// retracts. The batch is kept when the transaction fails so the next flush saves it again
+func (s *store) Flush(_ context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.batch) == 0 {
+		return nil
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, item := range s.batch {
+			if err := save(tx, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.batch = nil
+	return nil
+}
+
+// Query returns the events matching the query in block and log index order. The most selective index
+// is scanned and the rest of the conditions are checked on every event
+func (s *store) Query(query Query) ([]blockchain.Event, error) {
+	var result []blockchain.Event
+	err := s.db.View(func(tx *bolt.Tx) error {
+		events := tx.Bucket(eventsBucket)
+		if events == nil {
+			return nil
+		}
+		visit := func(key []byte, value []byte) (bool, error) {
+			event, err := blockchain.UnmarshalEvent(value)
+			if err != nil {
+				return false, err
+			}
+			if matches(event, query) {
+				result = append(result, event)
+			}
+			return query.Limit > 0 && len(result) >= query.Limit, nil
+		}
+
+		bucket, prefix := queryIndex(query)
+		if bucket == nil {
+			cursor := events.Cursor()
+			for key, value := cursor.Seek(blockKey(query.FromBlock)); key != nil; key, value = cursor.Next() {
+				if stop, err := visit(key, value); stop || err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		index := tx.Bucket(bucket)
+		if index == nil {
+			return nil
+		}
+		cursor := index.Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			eventKey := key[len(prefix):]
+			if stop, err := visit(eventKey, events.Get(eventKey)); stop || err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+// Close saves the batched events and closes the store file
+func (s *store) Close() error {
+	err := s.Flush(context.Background())
+	if errClose := s.db.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// save puts an event and its indexes, a retracted event deletes the event it retracts
+func save(tx *bolt.Tx, item batched) error {
+	if retracted, ok := item.event.(*blockchain.RetractedEvent); ok {
+		return remove(tx, retracted.Retracted)
+	}
+	key := eventKey(item.event.Log())
+	if err := tx.Bucket(eventsBucket).Put(key, item.value); err != nil {
+		return err
+	}
+	for bucket, prefix := range indexes(item.event) {
+		if err := tx.Bucket([]byte(bucket)).Put(append(prefix, key...), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove deletes an event and its indexes
+func remove(tx *bolt.Tx, event blockchain.Event) error {
+	key := eventKey(event.Log())
+	if err := tx.Bucket(eventsBucket).Delete(key); err != nil {
+		return err
+	}
+	for bucket, prefix := range indexes(event) {
+		if err := tx.Bucket([]byte(bucket)).Delete(append(prefix, key...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventKey returns the event key, the block and log index first so the events are sorted
+func eventKey(eventLog blockchain.EventLog) []byte {
+	key := make([]byte, 12, 12+common.AddressLength)
+	binary.BigEndian.PutUint64(key, eventLog.BlockNumber)
+	binary.BigEndian.PutUint32(key[8:], uint32(eventLog.LogIndex))
+	return append(key, common.HexToAddress(eventLog.Contract).Bytes()...)
+}
+
+// blockKey returns the first key of a block
+func blockKey(block uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, block)
+	return key
+}
+
+// indexes returns the index buckets and key prefixes of an event
+func indexes(event blockchain.Event) map[string][]byte {
+	result := map[string][]byte{
+		string(typeBucket): typePrefix(event.Type()),
+	}
+	switch e := event.(type) {
+	case *blockchain.AllowanceChangedEvent:
+		result[string(beneficiaryBucket)] = addressPrefix(e.Beneficiary)
+		result[string(senderBucket)] = addressPrefix(e.Sender)
+	case *blockchain.MoneySentEvent:
+		result[string(beneficiaryBucket)] = addressPrefix(e.Beneficiary)
+	case *blockchain.MoneyReceivedEvent:
+		result[string(senderBucket)] = addressPrefix(e.Sender)
+	}
+	return result
+}
+
+// queryIndex returns the most selective index and prefix for the query, nil if the events should be scanned
+func queryIndex(query Query) ([]byte, []byte) {
+	switch {
+	case query.Beneficiary != "":
+		return beneficiaryBucket, addressPrefix(query.Beneficiary)
+	case query.Sender != "":
+		return senderBucket, addressPrefix(query.Sender)
+	case query.Type != "":
+		return typeBucket, typePrefix(query.Type)
+	}
+	return nil, nil
+}
+
+func typePrefix(eventType string) []byte {
+	return append([]byte(eventType), 0)
+}
+
+func addressPrefix(address string) []byte {
+	return common.HexToAddress(address).Bytes()
+}
+
+// matches reports whether the event matches every condition of the query
+func matches(event blockchain.Event, query Query) bool {
+	eventLog := event.Log()
+	if query.Type != "" && event.Type() != query.Type {
+		return false
+	}
+	if query.Contract != "" && common.HexToAddress(eventLog.Contract) != common.HexToAddress(query.Contract) &&
+		eventLog.Label != query.Contract {
+		return false
+	}
+	if eventLog.BlockNumber < query.FromBlock || (query.ToBlock > 0 && eventLog.BlockNumber > query.ToBlock) {
+		return false
+	}
+	if !query.Since.IsZero() && eventLog.Timestamp.Before(query.Since) {
+		return false
+	}
+	if !query.Until.IsZero() && !eventLog.Timestamp.Before(query.Until) {
+		return false
+	}
+	beneficiary, sender := Parties(event)
+	if query.Beneficiary != "" && common.HexToAddress(beneficiary) != common.HexToAddress(query.Beneficiary) {
+		return false
+	}
+	if query.Sender != "" && common.HexToAddress(sender) != common.HexToAddress(query.Sender) {
+		return false
+	}
+	return true
+}
+
+// Parties returns the beneficiary and sender of an event, empty if the event does not have them
+func Parties(event blockchain.Event) (string, string) {
+	switch e := event.(type) {
+	case *blockchain.AllowanceChangedEvent:
+		return e.Beneficiary, e.Sender
+	case *blockchain.MoneySentEvent:
+		return e.Beneficiary, ""
+	case *blockchain.MoneyReceivedEvent:
+		return "", e.Sender
+	}
+	return "", ""
+}
+
+// Amount returns the amount of an event in wei, the new amount of the allowance changes and nil if the event does not
+// have it
+func Amount(event blockchain.Event) *big.Int {
+	switch e := event.(type) {
+	case *blockchain.AllowanceChangedEvent:
+		return e.NewAmount
+	case *blockchain.MoneySentEvent:
+		return e.Amount
+	case *blockchain.MoneyReceivedEvent:
+		return e.Amount
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"log"
+	"net"
+)
+
+const (
+	// EventsServiceName full name of the events service in events.proto
+	EventsServiceName = "wallet.v1.Events"
+	// maxExactFloat biggest integer a float64 holds exactly
+	maxExactFloat = 1 << 53
+)
+
+// amountFields event fields with a wei amount, they are sent as decimal strings whatever their size
+var amountFields = map[string]bool{"amount": true, "prev_amount": true, "new_amount": true}
+
+// EventsServiceDesc descriptor of the events service, the messages are google.protobuf.Struct so there is no generated code
+var EventsServiceDesc = grpc.ServiceDesc{
+	ServiceName: EventsServiceName,
+	HandlerType: (*EventsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       subscribeHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "events.proto",
+}
+
+// EventsServer interface of the events service
+type EventsServer interface {
+	Subscribe(request *structpb.Struct, stream grpc.ServerStream) error
+}
+
+type eventsServer struct {
+	hub *Hub
+}
+
+// ServeGRPC serves the events service until the context is done
+func ServeGRPC(ctx context.Context, addr string, hub *Hub) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	server.RegisterService(&EventsServiceDesc, &eventsServer{hub: hub})
+	go func() {
+		<-ctx.Done()
+		server.Stop()
+	}()
+	log.Printf("serving gRPC events at %s\n", addr)
+	return server.Serve(listener)
+}
+
+func subscribeHandler(srv interface{}, stream grpc.ServerStream) error {
+	request := new(structpb.Struct)
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+	return srv.(EventsServer).Subscribe(request, stream)
+}
+
+// Subscribe sends the replayed and live events matching the request filters
+func (s *eventsServer) Subscribe(request *structpb.Struct, stream grpc.ServerStream) error {
+	fields := request.GetFields()
+	filter := Filter{
+		Types:         listField(fields["types"]),
+		Contracts:     listField(fields["contracts"]),
+		Beneficiaries: listField(fields["beneficiaries"]),
+		Senders:       listField(fields["senders"]),
+	}
+	cursor, err := ParseCursor(fields["cursor"].GetStringValue())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	subscription, err := s.hub.Subscribe(filter, cursor)
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer s.hub.Unsubscribe(subscription)
+
+	for _, event := range subscription.Replay {
+		if err = sendEvent(stream, event); err != nil {
+			return err
+		}
+	}
+	for {
+		event, err := subscription.Next(stream.Context())
+		if err != nil {
+			if stream.Context().Err() != nil {
+				return nil
+			}
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		if err = sendEvent(stream, event); err != nil {
+			return err
+		}
+	}
+}
+
+// sendEvent sends the event as a struct with its cursor and type
+func sendEvent(stream grpc.ServerStream, event blockchain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&fields); err != nil {
+		return err
+	}
+	eventValue, err := structpb.NewValue(toProtoValue(fields))
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(&structpb.Struct{Fields: map[string]*structpb.Value{
+		"cursor": structpb.NewStringValue(Cursor(event)),
+		"type":   structpb.NewStringValue(event.Type()),
+		"event":  eventValue,
+	}})
+}
+
+// toProtoValue converts the JSON integers to float64, the wei amounts are kept as decimal strings so their type does
+// not depend on their size
+func toProtoValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if number, ok := item.(json.Number); ok && amountFields[key] {
+				v[key] = number.String()
+				continue
+			}
+			v[key] = toProtoValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = toProtoValue(item)
+		}
+		return v
+	case json.Number:
+		if n, err := v.Int64(); err == nil && n <= maxExactFloat && n >= -maxExactFloat {
+			return float64(n)
+		}
+		return v.String()
+	}
+	return value
+}
+
+// listField returns the strings of a list or string value
+func listField(value *structpb.Value) []string {
+	if value == nil {
+		return nil
+	}
+	if s, ok := value.GetKind().(*structpb.Value_StringValue); ok {
+		return values([]string{s.StringValue})
+	}
+	var result []string
+	for _, item := range value.GetListValue().GetValues() {
+		result = append(result, item.GetStringValue())
+	}
+	return values(result)
+}
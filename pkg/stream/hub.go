@@ -0,0 +1,263 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"github.com/StevenRojas/sharedWallet/pkg/store"
+	"github.com/ethereum/go-ethereum/common"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultBufferSize number of recent events kept to resume the subscriptions when there is no event store
+	DefaultBufferSize = 4096
+	// subscriberBuffer number of events queued for a subscriber, a slower subscriber is disconnected
+	subscriberBuffer = 256
+)
+
+var (
+	ErrInvalidCursor = errors.New("cursor should be block:log_index")
+	ErrSlowConsumer  = errors.New("subscriber is too slow, resume from the last cursor")
+	ErrHubClosed     = errors.New("event stream is closed")
+)
+
+// Filter struct, the empty lists are not used to filter. The contracts could be addresses or labels
+type Filter struct {
+	Types         []string
+	Contracts     []string
+	Beneficiaries []string
+	Senders       []string
+}
+
+// Matches reports whether the event matches the filter, a retracted event matches when the event it retracts does
+func (f Filter) Matches(event blockchain.Event) bool {
+	if retracted, ok := event.(*blockchain.RetractedEvent); ok && retracted.Retracted != nil {
+		if len(f.Types) > 0 && contains(f.Types, blockchain.Retracted) {
+			return f.matchesParties(event, retracted.Retracted)
+		}
+		return f.Matches(retracted.Retracted)
+	}
+	if len(f.Types) > 0 && !contains(f.Types, event.Type()) {
+		return false
+	}
+	return f.matchesParties(event, event)
+}
+
+// matchesParties checks the contract of the event and the beneficiary and sender of the original event
+func (f Filter) matchesParties(event blockchain.Event, original blockchain.Event) bool {
+	eventLog := event.Log()
+	if len(f.Contracts) > 0 && !contains(f.Contracts, eventLog.Label) && !containsAddress(f.Contracts, eventLog.Contract) {
+		return false
+	}
+	beneficiary, sender := store.Parties(original)
+	if len(f.Beneficiaries) > 0 && (beneficiary == "" || !containsAddress(f.Beneficiaries, beneficiary)) {
+		return false
+	}
+	if len(f.Senders) > 0 && (sender == "" || !containsAddress(f.Senders, sender)) {
+		return false
+	}
+	return true
+}
+
+// ParseCursor parses a block:log_index cursor, an empty cursor returns nil
+func ParseCursor(value string) (*blockchain.Position, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	block, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	logIndex, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &blockchain.Position{Block: block, LogIndex: uint(logIndex)}, nil
+}
+
+// Cursor returns the block:log_index cursor of an event, the log index is unique in the block so it is unique in the chain
+func Cursor(event blockchain.Event) string {
+	return fmt.Sprintf("%d:%d", event.Log().BlockNumber, event.Log().LogIndex)
+}
+
+// Subscription struct, the replayed events are followed by the live ones
+type Subscription struct {
+	Replay   []blockchain.Event
+	filter   Filter
+	events   chan blockchain.Event
+	err      error
+	replayed map[string]struct{}
+}
+
+// Next returns the next live event, skipping the ones already replayed. It returns an error when the subscription
+// is dropped or the hub is closed
+func (s *Subscription) Next(ctx context.Context) (blockchain.Event, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case event, ok := <-s.events:
+			if !ok {
+				return nil, s.err
+			}
+			if _, ok = s.replayed[key(event)]; ok {
+				continue
+			}
+			return event, nil
+		}
+	}
+}
+
+// Hub struct, it receives the monitor events as a sink and sends them to the subscribers
+type Hub struct {
+	mutex       sync.Mutex
+	buffer      []blockchain.Event
+	size        int
+	store       store.Store
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub creates a hub keeping the last size events, the older events are replayed from the store when it is not nil
+func NewHub(size int, events store.Store) *Hub {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Hub{
+		size:        size,
+		store:       events,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Write keeps the event and queues it for the subscribers without blocking, the slow subscribers are dropped
+func (h *Hub) Write(_ context.Context, event blockchain.Event) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.buffer = append(h.buffer, event)
+	if len(h.buffer) > h.size {
+		h.buffer = h.buffer[len(h.buffer)-h.size:]
+	}
+	for subscriber := range h.subscribers {
+		if !subscriber.filter.Matches(event) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			h.drop(subscriber, ErrSlowConsumer)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber, the events after the cursor are replayed from the store or the recent events
+func (h *Hub) Subscribe(filter Filter, cursor *blockchain.Position) (*Subscription, error) {
+	subscription := &Subscription{
+		filter:   filter,
+		events:   make(chan blockchain.Event, subscriberBuffer),
+		replayed: make(map[string]struct{}),
+	}
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return nil, ErrHubClosed
+	}
+	h.subscribers[subscription] = struct{}{}
+	recent := append([]blockchain.Event(nil), h.buffer...)
+	h.mutex.Unlock()
+	if cursor == nil {
+		return subscription, nil
+	}
+
+	if h.store != nil {
+		history, err := h.store.Query(store.Query{FromBlock: cursor.Block})
+		if err != nil {
+			h.Unsubscribe(subscription)
+			return nil, err
+		}
+		subscription.add(history, *cursor)
+	} else if len(recent) == h.size && position(recent[0]).After(*cursor) {
+		log.Printf("resuming a subscription from %d:%d, the older events are not kept\n", cursor.Block, cursor.LogIndex)
+	}
+	subscription.add(recent, *cursor)
+	return subscription, nil
+}
+
+// Unsubscribe removes a subscriber
+func (h *Hub) Unsubscribe(subscription *Subscription) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.drop(subscription, nil)
+}
+
+// Close ends every subscription
+func (h *Hub) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.closed = true
+	for subscriber := range h.subscribers {
+		h.drop(subscriber, ErrHubClosed)
+	}
+	return nil
+}
+
+// drop removes a subscriber and closes its events, the caller should hold the mutex
+func (h *Hub) drop(subscription *Subscription, err error) {
+	if _, ok := h.subscribers[subscription]; !ok {
+		return
+	}
+	delete(h.subscribers, subscription)
+	subscription.err = err
+	close(subscription.events)
+}
+
+// add appends the matching events after the cursor to the replay, skipping the ones already added
+func (s *Subscription) add(events []blockchain.Event, cursor blockchain.Position) {
+	for _, event := range events {
+		if !position(event).After(cursor) || !s.filter.Matches(event) {
+			continue
+		}
+		if _, ok := s.replayed[key(event)]; ok {
+			continue
+		}
+		s.replayed[key(event)] = struct{}{}
+		s.Replay = append(s.Replay, event)
+	}
+}
+
+// key identifies an event, a retracted event and the event of the new chain at the same position are different events
+func key(event blockchain.Event) string {
+	return fmt.Sprintf("%s:%d:%s", event.Log().BlockHash, event.Log().LogIndex, event.Type())
+}
+
+func position(event blockchain.Event) blockchain.Position {
+	return blockchain.Position{Block: event.Log().BlockNumber, LogIndex: event.Log().LogIndex}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAddress(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if common.IsHexAddress(a) && common.HexToAddress(a) == common.HexToAddress(address) {
+			return true
+		}
+	}
+	return false
+}
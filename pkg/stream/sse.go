@@ -0,0 +1,153 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/StevenRojas/sharedWallet/pkg/blockchain"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// heartbeatInterval time between two SSE comments keeping the idle connections open
+	heartbeatInterval = 15 * time.Second
+	// shutdownTimeout time waiting for the open requests when the server is stopped
+	shutdownTimeout = 5 * time.Second
+	// lastEventIDHeader header sent by the SSE clients when they reconnect
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// NewSSEHandler returns the handler streaming the hub events as Server-Sent Events. The filters are the type, contract,
+// beneficiary and sender query parameters, repeated or comma separated, and the resume cursor is the cursor parameter
+// or the Last-Event-ID header
+func NewSSEHandler(hub *Hub) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		query := r.URL.Query()
+		filter := Filter{
+			Types:         values(query["type"]),
+			Contracts:     values(query["contract"]),
+			Beneficiaries: values(query["beneficiary"]),
+			Senders:       values(query["sender"]),
+		}
+		cursorValue := query.Get("cursor")
+		if lastEventID := r.Header.Get(lastEventIDHeader); lastEventID != "" {
+			cursorValue = lastEventID
+		}
+		cursor, err := ParseCursor(cursorValue)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		subscription, err := hub.Subscribe(filter, cursor)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		defer hub.Unsubscribe(subscription)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		for _, event := range subscription.Replay {
+			if err = writeSSE(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		ctx := r.Context()
+		events := make(chan blockchain.Event)
+		errs := make(chan error, 1)
+		go func() {
+			for {
+				event, err := subscription.Next(ctx)
+				if err != nil {
+					errs <- err
+					return
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err = <-errs:
+				if err != nil && !errors.Is(err, context.Canceled) {
+					_, _ = fmt.Fprintf(w, "event: error\ndata: %s\n\n", err.Error())
+					flusher.Flush()
+				}
+				return
+			case event := <-events:
+				if err = writeSSE(w, event); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-heartbeat.C:
+				if _, err = fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
+
+// ServeSSE serves the event stream in /events until the context is done
+func ServeSSE(ctx context.Context, addr string, hub *Hub) error {
+	mux := http.NewServeMux()
+	mux.Handle("/events", NewSSEHandler(hub))
+	// the requests context is the server one so the open streams end when it is done
+	server := &http.Server{Addr: addr, Handler: mux, BaseContext: func(net.Listener) context.Context { return ctx }}
+	go func() {
+		<-ctx.Done()
+		ctxShutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(ctxShutdown)
+	}()
+	log.Printf("serving Server-Sent Events at %s/events\n", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// writeSSE writes an event with its cursor as id and its type as event name
+func writeSSE(w http.ResponseWriter, event blockchain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", Cursor(event), event.Type(), data)
+	return err
+}
+
+// values splits the comma separated query values
+func values(query []string) []string {
+	var result []string
+	for _, value := range query {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				result = append(result, v)
+			}
+		}
+	}
+	return result
+}